@@ -60,22 +60,53 @@ def ensure_table_exists(conn):
         sql = """
         CREATE TABLE IF NOT EXISTS click_stats (
             id BIGINT AUTO_INCREMENT PRIMARY KEY,
-            short_url VARCHAR(20),
+            domain VARCHAR(255) NOT NULL DEFAULT '',
+            short_url VARCHAR(64),
             long_url TEXT,
+            destination TEXT,
             ip VARCHAR(45),
             browser VARCHAR(50),
             os VARCHAR(50),
             device VARCHAR(50),
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            INDEX idx_domain_short_url (domain, short_url)
         ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
         """
         with conn.cursor() as cursor:
             cursor.execute(sql)
+            upgrade_table(cursor)
         logger.info("Table 'click_stats' checked/created.")
     except Exception as e:
         logger.error(f"Failed to create table: {e}")
         raise e
 
+def upgrade_table(cursor):
+    """升级旧版本创建的表：short_url 需容纳最长 64 个字符的自定义别名；
+    不同域名下可以有相同的短码，统计需按 (domain, short_url) 区分；
+    destination 记录本次跳转的最终地址，long_url 仍为链接保存的长链接"""
+    cursor.execute("""
+        SELECT column_name AS name, CHARACTER_MAXIMUM_LENGTH AS width FROM information_schema.columns
+        WHERE table_schema = DATABASE() AND table_name = 'click_stats'
+    """)
+    columns = {row['name'].lower(): row['width'] for row in cursor.fetchall()}
+    if columns.get('short_url') is not None and columns['short_url'] < 64:
+        cursor.execute("ALTER TABLE click_stats MODIFY short_url VARCHAR(64)")
+        logger.info("Widened click_stats.short_url to VARCHAR(64).")
+    if 'domain' not in columns:
+        cursor.execute("ALTER TABLE click_stats ADD COLUMN domain VARCHAR(255) NOT NULL DEFAULT '' AFTER id")
+        logger.info("Added click_stats.domain.")
+    if 'destination' not in columns:
+        cursor.execute("ALTER TABLE click_stats ADD COLUMN destination TEXT AFTER long_url")
+        logger.info("Added click_stats.destination.")
+
+    cursor.execute("""
+        SELECT 1 FROM information_schema.statistics
+        WHERE table_schema = DATABASE() AND table_name = 'click_stats' AND index_name = 'idx_domain_short_url'
+    """)
+    if not cursor.fetchone():
+        cursor.execute("ALTER TABLE click_stats ADD INDEX idx_domain_short_url (domain, short_url)")
+        logger.info("Added index idx_domain_short_url on click_stats.")
+
 def start_consumer():
     # 1. 准备数据库
     db_conn = get_db_connection()
@@ -114,7 +145,9 @@ def start_consumer():
             db_conn.ping(reconnect=True)
 
             data = message.value
-            logger.info(f"Processing event for short_url: {data.get('short_url')}")
+            # 默认域名的 domain 为空字符串，品牌域名为其小写 host
+            domain = data.get('domain') or ''
+            logger.info(f"Processing event for short_url: {domain}/{data.get('short_url')}")
 
             # 解析 UA
             ua_string = data.get('user_agent', '')
@@ -125,13 +158,15 @@ def start_consumer():
 
             # 入库
             sql = """
-            INSERT INTO click_stats (short_url, long_url, ip, browser, os, device)
-            VALUES (%s, %s, %s, %s, %s, %s)
+            INSERT INTO click_stats (domain, short_url, long_url, destination, ip, browser, os, device)
+            VALUES (%s, %s, %s, %s, %s, %s, %s, %s)
             """
             with db_conn.cursor() as cursor:
                 cursor.execute(sql, (
+                    domain,
                     data.get('short_url'),
                     data.get('long_url'),
+                    data.get('destination'),
                     data.get('ip'),
                     browser,
                     os_info,
@@ -10,19 +10,22 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/yin1895/tinylink/internal/config"
 	"github.com/yin1895/tinylink/internal/storage"
 	pb "github.com/yin1895/tinylink/pkg/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type idGeneratorServer struct {
 	pb.UnimplementedIdGeneratorServer
+	ids *segmentAllocator
 }
 
 func (s *idGeneratorServer) GenerateId(ctx context.Context, in *pb.Empty) (*pb.GenerateIdResponse, error) {
-	log.Println("Received request to generate a new ID.")
-	id, err := storage.GetNextID()
+	id, _, err := s.ids.Allocate(ctx, 1)
 	if err != nil {
 		log.Printf("Failed to get next ID: %v", err)
 		return nil, fmt.Errorf("internal storage error: %w", err)
@@ -30,31 +33,57 @@ func (s *idGeneratorServer) GenerateId(ctx context.Context, in *pb.Empty) (*pb.G
 	return &pb.GenerateIdResponse{Id: id}, nil
 }
 
+// maxIdsPerRequest 单次 GenerateIdRange 允许申请的最大数量
+const maxIdsPerRequest = 1000
+
+func (s *idGeneratorServer) GenerateIdRange(ctx context.Context, in *pb.GenerateIdRangeRequest) (*pb.GenerateIdRangeResponse, error) {
+	count := int64(in.GetCount())
+	if count <= 0 || count > maxIdsPerRequest {
+		return nil, status.Errorf(codes.InvalidArgument, "count must be between 1 and %d", maxIdsPerRequest)
+	}
+	start, n, err := s.ids.Allocate(ctx, count)
+	if err != nil {
+		log.Printf("Failed to allocate ID range: %v", err)
+		return nil, fmt.Errorf("internal storage error: %w", err)
+	}
+	return &pb.GenerateIdRangeResponse{Start: start, Count: int32(n)}, nil
+}
+
 func main() {
-	// 1. 初始化数据库
-	if err := storage.InitMySQL(); err != nil {
-		log.Fatalf("Fatal: Failed to connect to MySQL: %v", err)
+	// 1. 加载配置 (默认值 < 配置文件 < 环境变量 < 命令行参数)
+	cfg, _, err := config.Load("id-generator", os.Args[1:])
+	if err != nil {
+		log.Fatalf("Fatal: %v", err)
+	}
+
+	// 2. 初始化数据库
+	store, err := storage.OpenSQLStore(cfg.Database)
+	if err != nil {
+		log.Fatalf("Fatal: Failed to connect to database: %v", err)
 	}
 	// 退出时关闭数据库连接
 	defer func() {
-		if storage.Db != nil {
-			storage.Db.Close()
-			log.Println("MySQL connection closed.")
-		}
+		store.Close()
+		log.Println("Database connection closed.")
 	}()
 
-	// 2. 监听端口
-	port := ":50051"
-	lis, err := net.Listen("tcp", port)
+	// 3. 初始化号段
+	if err := store.InitSegment(context.Background(), storage.SegmentTagURLs); err != nil {
+		log.Fatalf("Fatal: Failed to initialize ID segment: %v", err)
+	}
+	allocator := newSegmentAllocator(store, storage.SegmentTagURLs, cfg.IDGenerator.SegmentStep)
+
+	// 4. 监听端口
+	lis, err := net.Listen("tcp", cfg.IDGenerator.ListenAddr)
 	if err != nil {
-		log.Fatalf("Fatal: Failed to listen on port %s: %v", port, err)
+		log.Fatalf("Fatal: Failed to listen on %s: %v", cfg.IDGenerator.ListenAddr, err)
 	}
 
-	// 3. 创建 gRPC 服务器
+	// 5. 创建 gRPC 服务器
 	s := grpc.NewServer()
-	pb.RegisterIdGeneratorServer(s, &idGeneratorServer{})
+	pb.RegisterIdGeneratorServer(s, &idGeneratorServer{ids: allocator})
 
-	// 4. 在 Goroutine 中启动服务
+	// 6. 在 Goroutine 中启动服务
 	go func() {
 		log.Printf("gRPC server listening at %v", lis.Addr())
 		if err := s.Serve(lis); err != nil {
@@ -62,13 +91,13 @@ func main() {
 		}
 	}()
 
-	// 5. 监听停止信号
+	// 7. 监听停止信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down gRPC server...")
 
-	// 6. 停机逻辑
+	// 8. 停机逻辑
 	// GracefulStop 会停止接收新连接，并阻塞直到所有待处理的 RPC 调用完成
 	s.GracefulStop()
 
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// segmentSource 号段的持久化来源，由存储层实现
+type segmentSource interface {
+	FetchSegment(ctx context.Context, tag string, step int64) (int64, error)
+}
+
+// segment 一段已从数据库预留的连续 ID，[next, max] 为尚未发放的部分
+type segment struct {
+	start int64
+	next  int64
+	max   int64
+}
+
+func (s *segment) remaining() int64 {
+	return s.max - s.next + 1
+}
+
+// segmentAllocator Leaf-segment 风格的 ID 分配器
+// 每次数据库往返预留 step 个 ID，并采用双缓冲：
+// 当前号段消耗超过 prefetchRatio 时异步加载下一个号段，切换时无需等待数据库
+type segmentAllocator struct {
+	source segmentSource
+	tag    string
+	step   int64
+
+	mu       sync.Mutex
+	cur      *segment
+	buffered *segment      // 预取好的下一个号段
+	loading  chan struct{} // 非 nil 表示预取进行中，完成后关闭
+}
+
+// prefetchRatio 当前号段消耗比例超过该值时触发预取
+const prefetchRatio = 0.1
+
+func newSegmentAllocator(source segmentSource, tag string, step int64) *segmentAllocator {
+	return &segmentAllocator{source: source, tag: tag, step: step}
+}
+
+// Allocate 分配最多 count 个连续 ID，返回起始 ID 和实际数量
+// 实际数量不会超过当前号段剩余量，调用方需要更多 ID 时应再次调用
+func (a *segmentAllocator) Allocate(ctx context.Context, count int64) (int64, int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for a.cur == nil || a.cur.remaining() == 0 {
+		if a.buffered != nil {
+			a.cur, a.buffered = a.buffered, nil
+			continue
+		}
+		if loading := a.loading; loading != nil {
+			// 预取进行中，等待其完成而不是重复加载；预取失败时下一轮循环会同步加载
+			a.mu.Unlock()
+			select {
+			case <-loading:
+			case <-ctx.Done():
+				a.mu.Lock()
+				return 0, 0, ctx.Err()
+			}
+			a.mu.Lock()
+			continue
+		}
+		seg, err := a.load(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		a.cur = seg
+	}
+
+	n := min(count, a.cur.remaining())
+	start := a.cur.next
+	a.cur.next += n
+
+	used := float64(a.cur.next-a.cur.start) / float64(a.cur.max-a.cur.start+1)
+	if a.buffered == nil && a.loading == nil && used >= prefetchRatio {
+		a.loading = make(chan struct{})
+		go a.prefetch(a.loading)
+	}
+	return start, n, nil
+}
+
+// prefetch 异步加载下一个号段
+func (a *segmentAllocator) prefetch(done chan struct{}) {
+	seg, err := a.load(context.Background())
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err != nil {
+		log.Printf("Failed to prefetch ID segment: %v", err)
+	} else {
+		a.buffered = seg
+	}
+	a.loading = nil
+	close(done)
+}
+
+// load 从数据库预留一个新号段
+func (a *segmentAllocator) load(ctx context.Context) (*segment, error) {
+	maxID, err := a.source.FetchSegment(ctx, a.tag, a.step)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Loaded ID segment (%d, %d]", maxID-a.step, maxID)
+	start := maxID - a.step + 1
+	return &segment{start: start, next: start, max: maxID}, nil
+}
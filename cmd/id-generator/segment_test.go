@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSource 内存中的号段来源，模拟数据库中持续推进的号段上界；
+// gate 非空时每次加载先等待 gate 放行，err 非空时加载失败
+type fakeSource struct {
+	mu    sync.Mutex
+	max   int64
+	calls int
+	gate  chan struct{}
+	err   error
+}
+
+func (s *fakeSource) FetchSegment(ctx context.Context, tag string, step int64) (int64, error) {
+	s.mu.Lock()
+	gate := s.gate
+	s.mu.Unlock()
+	if gate != nil {
+		select {
+		case <-gate:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.err != nil {
+		return 0, s.err
+	}
+	s.max += step
+	return s.max, nil
+}
+
+func (s *fakeSource) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// waitBuffered 等待后台预取完成
+func waitBuffered(t *testing.T, a *segmentAllocator) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		a.mu.Lock()
+		buffered := a.buffered != nil
+		a.mu.Unlock()
+		if buffered {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("next segment was not prefetched")
+}
+
+// TestSegmentRollover 号段用完后切换到预取好的下一个号段，单次分配不跨号段
+func TestSegmentRollover(t *testing.T) {
+	source := &fakeSource{}
+	a := newSegmentAllocator(source, "urls", 10)
+
+	start, n, err := a.Allocate(context.Background(), 4)
+	if err != nil || start != 1 || n != 4 {
+		t.Fatalf("Allocate(4) = %d, %d, %v, want 1, 4", start, n, err)
+	}
+	waitBuffered(t, a) // 消耗超过 prefetchRatio，后台加载 (10, 20]
+
+	// 当前号段只剩 6 个，不跨号段分配
+	start, n, err = a.Allocate(context.Background(), 8)
+	if err != nil || start != 5 || n != 6 {
+		t.Fatalf("Allocate(8) = %d, %d, %v, want 5, 6", start, n, err)
+	}
+	start, n, err = a.Allocate(context.Background(), 8)
+	if err != nil || start != 11 || n != 8 {
+		t.Fatalf("Allocate(8) after rollover = %d, %d, %v, want 11, 8", start, n, err)
+	}
+	if calls := source.callCount(); calls != 2 {
+		t.Errorf("%d segment loads, want 2", calls)
+	}
+}
+
+// TestSegmentWaitsForPrefetch 当前号段用完而预取仍在进行时等待预取，不重复加载
+func TestSegmentWaitsForPrefetch(t *testing.T) {
+	source := &fakeSource{}
+	a := newSegmentAllocator(source, "urls", 100)
+	// 消耗未超过 prefetchRatio，不触发预取
+	if _, _, err := a.Allocate(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	source.mu.Lock()
+	source.gate = make(chan struct{})
+	source.mu.Unlock()
+
+	// 取完当前号段，触发的预取被挡住
+	if _, n, err := a.Allocate(context.Background(), 99); err != nil || n != 99 {
+		t.Fatalf("Allocate(99) = %d, %v", n, err)
+	}
+	done := make(chan int64, 1)
+	go func() {
+		start, _, _ := a.Allocate(context.Background(), 1)
+		done <- start
+	}()
+	select {
+	case <-done:
+		t.Fatal("Allocate returned before the prefetch finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(source.gate)
+	select {
+	case start := <-done:
+		if start != 101 {
+			t.Errorf("Allocate after prefetch = %d, want 101", start)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Allocate did not return after the prefetch finished")
+	}
+	if calls := source.callCount(); calls != 2 {
+		t.Errorf("%d segment loads, want 2", calls)
+	}
+}
+
+// TestSegmentLoadError 加载失败时返回错误，恢复后继续分配
+func TestSegmentLoadError(t *testing.T) {
+	source := &fakeSource{err: errors.New("db down")}
+	a := newSegmentAllocator(source, "urls", 10)
+	if _, _, err := a.Allocate(context.Background(), 1); err == nil {
+		t.Fatal("Allocate succeeded, want error")
+	}
+
+	source.mu.Lock()
+	source.err = nil
+	source.mu.Unlock()
+	if start, n, err := a.Allocate(context.Background(), 1); err != nil || n != 1 || start < 1 {
+		t.Errorf("Allocate after recovery = %d, %d, %v", start, n, err)
+	}
+}
+
+// TestSegmentConcurrentAllocate 并发分配的 ID 不重复且连续覆盖所有已加载号段
+func TestSegmentConcurrentAllocate(t *testing.T) {
+	source := &fakeSource{}
+	a := newSegmentAllocator(source, "urls", 50)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = make(map[int64]bool)
+	)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				start, n, err := a.Allocate(context.Background(), 3)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				for id := start; id < start+n; id++ {
+					if seen[id] {
+						t.Errorf("ID %d allocated twice", id)
+					}
+					seen[id] = true
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 号段不足时返回的数量少于 3，总数在 [1000, 3000] 之间，且从 1 开始没有空洞
+	if len(seen) < 1000 || len(seen) > 3000 {
+		t.Fatalf("allocated %d IDs", len(seen))
+	}
+	for id := int64(1); id <= int64(len(seen)); id++ {
+		if !seen[id] {
+			t.Fatalf("ID %d skipped", id)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/yin1895/tinylink/internal/storage"
+)
+
+// aliasPattern 自定义别名只允许字母、数字、'-' 和 '_'
+var aliasPattern = regexp.MustCompile(`^[0-9A-Za-z_-]{3,64}$`)
+
+// reservedAliases 与路由或常用路径冲突的保留字 (小写比较)
+var reservedAliases = map[string]struct{}{
+	"shorten": {},
+	"metrics": {},
+	"api":     {},
+	"admin":   {},
+	"health":  {},
+	"static":  {},
+}
+
+var (
+	errAliasInvalid  = errors.New("alias must be 3-64 characters of letters, digits, '-' or '_'")
+	errAliasReserved = errors.New("alias is reserved")
+)
+
+// validateAlias 校验别名格式及保留字
+func validateAlias(alias string) error {
+	if !aliasPattern.MatchString(alias) {
+		return errAliasInvalid
+	}
+	if _, ok := reservedAliases[strings.ToLower(alias)]; ok {
+		return errAliasReserved
+	}
+	return nil
+}
+
+// aliasTaken 检查别名在域名内是否已被其他别名占用，或与该域名下某个已分配 ID 的短码相同
+func (h *Handler) aliasTaken(ctx context.Context, domain, alias string) (bool, error) {
+	exists, err := h.aliasExists(ctx, domain, alias)
+	if err != nil || exists {
+		return exists, err
+	}
+	id, ok := h.Codec.Decode(alias)
+	if !ok {
+		return false, nil
+	}
+	return h.codeInUse(ctx, domain, id)
+}
+
+// aliasExists 判断别名在域名内是否已被使用
+func (h *Handler) aliasExists(ctx context.Context, domain, alias string) (bool, error) {
+	_, err := h.Store.GetLinkByAlias(ctx, domain, alias)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// codeInUse 判断域名下某个 ID 是否已存有链接
+func (h *Handler) codeInUse(ctx context.Context, domain string, id int64) (bool, error) {
+	link, err := h.Store.GetLink(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil && link.Domain == domain, err
+}
+
+// codesShadowedByAlias 返回自动生成的短码中与域名内已有别名重名的集合
+// 先用布隆过滤器一次批量排除，只为命中的短码发起一条数据库查询，绝大多数情况下无需访问数据库
+func (h *Handler) codesShadowedByAlias(ctx context.Context, domain string, codes []string) (map[string]bool, error) {
+	keys := make([]string, len(codes))
+	for i, code := range codes {
+		keys[i] = scopedKey(domain, code)
+	}
+	exists, err := h.Bloom.ExistsMany(keys)
+
+	// 布隆过滤器不可用时全部交给数据库确认
+	var hits []string
+	for i, code := range codes {
+		if err != nil || exists[i] {
+			hits = append(hits, code)
+		}
+	}
+	if len(hits) == 0 {
+		return nil, nil
+	}
+
+	aliases, err := h.Store.FindAliases(ctx, domain, hits)
+	if err != nil {
+		return nil, err
+	}
+	shadowed := make(map[string]bool, len(aliases))
+	for _, alias := range aliases {
+		shadowed[alias] = true
+	}
+	return shadowed, nil
+}
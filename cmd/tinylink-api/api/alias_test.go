@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		alias string
+		want  error
+	}{
+		{"promo", nil},
+		{"Summer_Sale-2026", nil},
+		{"abc", nil},
+		{strings.Repeat("a", 64), nil},
+		{"ab", errAliasInvalid},
+		{strings.Repeat("a", 65), errAliasInvalid},
+		{"has space", errAliasInvalid},
+		{"dot.ted", errAliasInvalid},
+		{"slash/ed", errAliasInvalid},
+		{"ünïcode", errAliasInvalid},
+		{"shorten", errAliasReserved},
+		{"Metrics", errAliasReserved},
+		{"API", errAliasReserved},
+	}
+	for _, tt := range tests {
+		if got := validateAlias(tt.alias); got != tt.want {
+			t.Errorf("validateAlias(%q) = %v, want %v", tt.alias, got, tt.want)
+		}
+	}
+}
+
+func TestShortenAlias(t *testing.T) {
+	s := newTestServer(t)
+	// ID 238328 编码为 "1000"，占用该短码的链接使同名别名不可用
+	s.save(t, &storage.Link{ID: 238328, LongURL: "https://example.com/taken"})
+	if code := s.shorten(t, gin.H{"url": "https://example.com/", "alias": "promo"}); code != "promo" {
+		t.Fatalf("short code = %q, want promo", code)
+	}
+
+	tests := []struct {
+		name string
+		body gin.H
+		want int
+	}{
+		{"alias in use", gin.H{"url": "https://example.com/other", "alias": "promo"}, http.StatusConflict},
+		{"alias matches an ID code", gin.H{"url": "https://example.com/other", "alias": "1000"}, http.StatusConflict},
+		{"same alias on another domain", gin.H{"url": "https://example.com/other", "alias": "promo", "domain": "go.brand.test"}, http.StatusOK},
+		{"reserved", gin.H{"url": "https://example.com/", "alias": "admin"}, http.StatusBadRequest},
+		{"invalid", gin.H{"url": "https://example.com/", "alias": "a b"}, http.StatusBadRequest},
+		{"unused ID code", gin.H{"url": "https://example.com/", "alias": "1001"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := s.do(t, http.MethodPost, "/shorten", tt.body); w.Code != tt.want {
+			t.Errorf("%s: %d %s, want %d", tt.name, w.Code, w.Body, tt.want)
+		}
+	}
+}
+
+// TestGeneratedCodeSkipsAlias 自动生成的短码与域名内已有别名相同时跳过该 ID
+func TestGeneratedCodeSkipsAlias(t *testing.T) {
+	s := newTestServer(t)
+	s.shorten(t, gin.H{"url": "https://example.com/alias", "alias": "1000"})
+	s.shorten(t, gin.H{"url": "https://example.com/brand", "alias": "1001", "domain": "go.brand.test"})
+	s.h.IDs = NewSequenceIDs(238328) // 之后生成的短码依次为 "1000"、"1001"、"1002"
+
+	// 1000 是默认域名的别名，被跳过；1001 只是品牌域名的别名，在默认域名仍可使用
+	if code := s.shorten(t, gin.H{"url": "https://example.com/a"}); code != "1001" {
+		t.Errorf("generated code = %q, want 1001", code)
+	}
+	if code := s.shorten(t, gin.H{"url": "https://example.com/b"}); code != "1002" {
+		t.Errorf("next generated code = %q, want 1002", code)
+	}
+}
+
+func TestRedirectAlias(t *testing.T) {
+	s := newTestServer(t)
+	s.save(t,
+		&storage.Link{ID: 1, LongURL: "https://example.com/promo", Alias: "promo"},
+		&storage.Link{ID: 2, LongURL: "https://example.com/brand", Alias: "brand", Domain: "go.brand.test"},
+	)
+	tests := []struct {
+		name, path string
+		want       int
+	}{
+		{"alias", "/promo", http.StatusFound},
+		{"alias link by its ID code", "/" + s.h.Codec.Encode(1), http.StatusNotFound},
+		{"unknown code", "/zzzz", http.StatusNotFound},
+		{"alias on its domain", testBrandURL + "/brand", http.StatusFound},
+		{"alias on another domain", "/brand", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := s.visit(tt.path, uaDesktop, "10.0.0.1"); w.Code != tt.want {
+			t.Errorf("%s: GET %s = %d, want %d", tt.name, tt.path, w.Code, tt.want)
+		}
+	}
+}
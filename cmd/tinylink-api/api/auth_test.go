@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAPIKeyOwnership 启用认证时链接归属于 key 的持有者，他人的链接在管理接口中不可见
+func TestAPIKeyOwnership(t *testing.T) {
+	s := newTestServer(t, apiKeyAuth(t, "alice", "bob"))
+
+	if w := s.do(t, http.MethodPost, "/shorten", gin.H{"url": "https://example.com/"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("shorten without a key = %d, want 401", w.Code)
+	}
+	w := s.doAs(t, "alice", http.MethodPost, "/shorten", gin.H{"url": "https://example.com/alice"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("alice: POST /shorten = %d %s", w.Code, w.Body)
+	}
+	shortURL, _ := decodeBody(t, w)["short_url"].(string)
+	code := strings.TrimPrefix(shortURL, testBaseURL+"/")
+	s.doAs(t, "bob", http.MethodPost, "/shorten", gin.H{"url": "https://example.com/bob"})
+
+	if link, err := s.store.GetLink(t.Context(), 1); err != nil || link.OwnerID != "alice" {
+		t.Errorf("stored link = %+v, %v, want owner alice", link, err)
+	}
+
+	// 他人的链接返回 404，不暴露其是否存在
+	target := "/api/links/" + code
+	tests := []struct {
+		owner, method string
+		body          any
+		want          int
+	}{
+		{"bob", http.MethodGet, nil, http.StatusNotFound},
+		{"bob", http.MethodPatch, gin.H{"url": "https://evil.example/"}, http.StatusNotFound},
+		{"bob", http.MethodDelete, nil, http.StatusNotFound},
+		{"alice", http.MethodGet, nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := s.doAs(t, tt.owner, tt.method, target, tt.body); w.Code != tt.want {
+			t.Errorf("%s: %s %s = %d, want %d", tt.owner, tt.method, target, w.Code, tt.want)
+		}
+	}
+
+	for owner, want := range map[string]string{"alice": "https://example.com/alice", "bob": "https://example.com/bob"} {
+		w := s.doAs(t, owner, http.MethodGet, "/api/links", nil)
+		links, _ := decodeBody(t, w)["links"].([]any)
+		if len(links) != 1 || links[0].(map[string]any)["url"] != want {
+			t.Errorf("%s: GET /api/links = %s, want only %s", owner, w.Body, want)
+		}
+	}
+
+	// 跳转不需要认证
+	if w := s.visit("/"+code, uaDesktop, "10.0.0.1"); w.Header().Get("Location") != "https://example.com/alice" {
+		t.Errorf("visit = %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBatchSize 单次批量生成的最大链接数，与 ID 生成器单次申请上限一致
+const maxBatchSize = 1000
+
+// batchInsertChunk 每条多行 INSERT 写入的行数，失败时只影响本批次
+const batchInsertChunk = 200
+
+// batchResult 批量生成中单个条目的结果
+type batchResult struct {
+	Index    int    `json:"index"`
+	URL      string `json:"url"`
+	ShortURL string `json:"short_url,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BatchShorten 批量生成短链接 POST /shorten/batch
+// 从本地号段一次取出全部 ID，多行 INSERT 写库，逐条返回结果 (部分失败不影响其他条目)
+func (h *Handler) BatchShorten(c *gin.Context) {
+	var req struct {
+		URLs       []string   `json:"urls"`
+		Domain     string     `json:"domain"`
+		ExpiresAt  *time.Time `json:"expires_at"`
+		TTLSeconds int64      `json:"ttl_seconds"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(err)})
+		return
+	}
+	if req.URLs == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "urls is required"})
+		return
+	}
+	if len(req.URLs) == 0 || len(req.URLs) > maxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "urls must contain between 1 and 1000 items"})
+		return
+	}
+
+	domain, err := h.Domains.Lookup(req.Domain)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expiresAt, err := resolveExpiry(req.ExpiresAt, req.TTLSeconds, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 1. 逐条校验，只为合法条目申请 ID
+	results := make([]batchResult, len(req.URLs))
+	var pending []int
+	for i, u := range req.URLs {
+		results[i] = batchResult{Index: i, URL: u}
+		longURL, err := h.checkLongURL(u)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].URL = longURL // 返回规范化后的地址
+		pending = append(pending, i)
+	}
+
+	// 2. 按合法条目数预占额度，一次取出全部 ID (本地号段不足时才发起 RPC)
+	owner := ownerID(c)
+	var ids []int64
+	release := func(int) {}
+	if len(pending) > 0 {
+		if release = h.reserveQuota(c, owner, len(pending)); release == nil {
+			return
+		}
+		ids, err = h.generateIDs(c.Request.Context(), domain, len(pending))
+		if err != nil {
+			release(len(pending))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to communicate with ID service"})
+			return
+		}
+	}
+
+	// 3. 分批多行写入，某批失败时仅标记该批条目
+	var keys []string
+	for start := 0; start < len(pending); start += batchInsertChunk {
+		end := min(start+batchInsertChunk, len(pending))
+		links := make([]*storage.Link, 0, end-start)
+		for k := start; k < end; k++ {
+			links = append(links, &storage.Link{ID: ids[k], LongURL: results[pending[k]].URL, Domain: domain, OwnerID: owner, ExpiresAt: expiresAt})
+		}
+		if err := h.Store.SaveLinks(c.Request.Context(), links); err != nil {
+			for k := start; k < end; k++ {
+				results[pending[k]].Error = "Failed to save URL"
+			}
+			continue
+		}
+		for k := start; k < end; k++ {
+			code := h.Codec.Encode(ids[k])
+			results[pending[k]].ShortURL = h.Domains.ShortURL(domain, code)
+			keys = append(keys, scopedKey(domain, code))
+		}
+	}
+
+	release(len(pending) - len(keys)) // 退还写入失败条目的额度
+	h.Bloom.AddBatch(keys)            // 批量加入布隆过滤器
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":   results,
+		"succeeded": len(keys),
+		"failed":    len(results) - len(keys),
+	})
+}
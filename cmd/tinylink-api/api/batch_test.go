@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBatchShorten(t *testing.T) {
+	s := newTestServer(t)
+	w := s.do(t, http.MethodPost, "/shorten/batch", gin.H{
+		"urls":        []string{"https://example.com/a", "ftp://example.com/", "HTTPS://Example.com:443/b", testBaseURL + "/1"},
+		"ttl_seconds": 60,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /shorten/batch: %d %s", w.Code, w.Body)
+	}
+	body := decodeBody(t, w)
+	if body["succeeded"] != 2.0 || body["failed"] != 2.0 {
+		t.Errorf("succeeded/failed = %v/%v, want 2/2", body["succeeded"], body["failed"])
+	}
+
+	results := body["results"].([]any)
+	want := []struct {
+		url, shortURL string
+		failed        bool
+	}{
+		{"https://example.com/a", testBaseURL + "/1", false},
+		{"ftp://example.com/", "", true},
+		{"https://example.com/b", testBaseURL + "/2", false},
+		{testBaseURL + "/1", "", true},
+	}
+	for i, tt := range want {
+		r := results[i].(map[string]any)
+		shortURL, _ := r["short_url"].(string)
+		if r["index"] != float64(i) || r["url"] != tt.url || shortURL != tt.shortURL || (r["error"] != nil) != tt.failed {
+			t.Errorf("results[%d] = %v, want url %q short_url %q failed %v", i, r, tt.url, tt.shortURL, tt.failed)
+		}
+	}
+
+	if w := s.visit("/2", uaDesktop, "10.0.0.1"); w.Header().Get("Location") != "https://example.com/b" {
+		t.Errorf("visit /2: %d Location %q", w.Code, w.Header().Get("Location"))
+	}
+	if link, err := s.store.GetLink(context.Background(), 1); err != nil || link.ExpiresAt == nil {
+		t.Errorf("GetLink(1) = %+v, %v, want an expiring link", link, err)
+	}
+}
+
+func TestBatchShortenDomain(t *testing.T) {
+	s := newTestServer(t)
+	w := s.do(t, http.MethodPost, "/shorten/batch", gin.H{"urls": []string{"https://example.com/"}, "domain": "go.brand.test"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /shorten/batch: %d %s", w.Code, w.Body)
+	}
+	r := decodeBody(t, w)["results"].([]any)[0].(map[string]any)
+	if r["short_url"] != testBrandURL+"/1" {
+		t.Errorf("short_url = %v, want %s/1", r["short_url"], testBrandURL)
+	}
+	if w := s.visit(testBrandURL+"/1", uaDesktop, "10.0.0.1"); w.Code != http.StatusFound {
+		t.Errorf("visit on the brand domain = %d, want 302", w.Code)
+	}
+	if w := s.visit("/1", uaDesktop, "10.0.0.1"); w.Code != http.StatusNotFound {
+		t.Errorf("visit on the default domain = %d, want 404", w.Code)
+	}
+
+	w = s.do(t, http.MethodPost, "/shorten/batch", gin.H{"urls": []string{"https://example.com/"}, "domain": "other.test"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown domain = %d, want 400", w.Code)
+	}
+}
+
+func TestBatchShortenQuota(t *testing.T) {
+	s := newTestServer(t)
+	s.h.Quotas = NewQuotas(s.store, nil, Plan{MonthlyLinks: 3})
+
+	// 非法条目不占用额度
+	w := s.do(t, http.MethodPost, "/shorten/batch", gin.H{"urls": []string{"https://example.com/a", "not a url", "https://example.com/b"}})
+	if w.Code != http.StatusOK || decodeBody(t, w)["succeeded"] != 2.0 {
+		t.Fatalf("first batch: %d %s", w.Code, w.Body)
+	}
+	w = s.do(t, http.MethodPost, "/shorten/batch", gin.H{"urls": []string{"https://example.com/c", "https://example.com/d"}})
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), errMonthlyQuota.Error()) {
+		t.Errorf("batch over quota = %d %s, want 403", w.Code, w.Body)
+	}
+	s.shorten(t, gin.H{"url": "https://example.com/c"}) // 超额的批量请求没有占用剩余额度
+}
+
+// countingFilter 统计布隆过滤器的查询次数
+type countingFilter struct {
+	storage.Filter
+	exists, existsMany atomic.Int32
+}
+
+func (f *countingFilter) Exists(data string) (bool, error) {
+	f.exists.Add(1)
+	return f.Filter.Exists(data)
+}
+
+func (f *countingFilter) ExistsMany(items []string) ([]bool, error) {
+	f.existsMany.Add(1)
+	return f.Filter.ExistsMany(items)
+}
+
+// countingStore 统计别名批量查询的次数
+type countingStore struct {
+	storage.LinkStore
+	findAliases atomic.Int32
+}
+
+func (s *countingStore) FindAliases(ctx context.Context, domain string, aliases []string) ([]string, error) {
+	s.findAliases.Add(1)
+	return s.LinkStore.FindAliases(ctx, domain, aliases)
+}
+
+// TestBatchShortenSkipsAliases 与别名重名的 ID 被跳过，每批 ID 只查询一次布隆过滤器，命中的短码合并为一次数据库查询
+func TestBatchShortenSkipsAliases(t *testing.T) {
+	s := newTestServer(t)
+	s.save(t,
+		&storage.Link{ID: 1, LongURL: "https://example.com/x", Alias: "1001"},
+		&storage.Link{ID: 2, LongURL: "https://example.com/y", Alias: "1003"},
+	)
+	filter := &countingFilter{Filter: s.h.Bloom}
+	store := &countingStore{LinkStore: s.store}
+	s.h.Bloom, s.h.Store = filter, store
+	s.h.IDs = NewSequenceIDs(238328) // 编码为 "1000"
+
+	urls := make([]string, 5)
+	for i := range urls {
+		urls[i] = "https://example.com/" + string(rune('a'+i))
+	}
+	w := s.do(t, http.MethodPost, "/shorten/batch", gin.H{"urls": urls})
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /shorten/batch: %d %s", w.Code, w.Body)
+	}
+	var got []string
+	for _, r := range decodeBody(t, w)["results"].([]any) {
+		got = append(got, strings.TrimPrefix(r.(map[string]any)["short_url"].(string), testBaseURL+"/"))
+	}
+	if strings.Join(got, ",") != "1000,1002,1004,1005,1006" {
+		t.Errorf("codes = %v, want 1000,1002,1004,1005,1006", got)
+	}
+	// 第一批 5 个 ID 中有 2 个被跳过，补充的第二批不再命中别名
+	if n := filter.exists.Load(); n != 0 {
+		t.Errorf("Exists called %d times, want 0", n)
+	}
+	if n := filter.existsMany.Load(); n != 2 {
+		t.Errorf("ExistsMany called %d times, want 2 (one per block)", n)
+	}
+	if n := store.findAliases.Load(); n != 1 {
+		t.Errorf("FindAliases called %d times, want 1", n)
+	}
+
+	for _, alias := range []string{"1001", "1003"} {
+		if w := s.visit("/"+alias, uaDesktop, "10.0.0.1"); w.Code != http.StatusFound {
+			t.Errorf("visit alias %s = %d, want 302", alias, w.Code)
+		}
+	}
+}
+
+func TestCodesShadowedByAlias(t *testing.T) {
+	s := newTestServer(t)
+	s.save(t,
+		&storage.Link{ID: 1, LongURL: "https://example.com/", Alias: "promo"},
+		&storage.Link{ID: 2, LongURL: "https://example.com/", Alias: "sale", Domain: "go.brand.test"},
+	)
+	shadowed, err := s.h.codesShadowedByAlias(context.Background(), "", []string{"promo", "sale", "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shadowed) != 1 || !shadowed["promo"] {
+		t.Errorf("codesShadowedByAlias = %v, want only promo (sale belongs to another domain)", shadowed)
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/yin1895/tinylink/internal/storage"
+)
+
+// clickCounterTTL Redis 中访问计数的有效期，过期后从数据库重新初始化，
+// 避免为长期无人访问或已删除的链接一直保留计数
+const clickCounterTTL = 7 * 24 * time.Hour
+
+// claimClick 为限次链接计入一次访问，次数已用完时返回 false
+// 优先在 Redis 中原子计数并异步同步到数据库；Redis 不可用时直接在数据库中原子计数
+func (h *Handler) claimClick(ctx context.Context, id, maxClicks int64) (bool, error) {
+	if h.Clicks == nil {
+		return h.Store.ClaimClick(ctx, id)
+	}
+
+	ok, err := h.Clicks.Claim(ctx, id, maxClicks)
+	if errors.Is(err, storage.ErrCounterMissing) {
+		ok, err = h.seedClicks(ctx, id, maxClicks)
+	}
+	if err != nil {
+		log.Printf("Click counter unavailable, falling back to database: %v", err)
+		return h.Store.ClaimClick(ctx, id)
+	}
+	if ok {
+		go func() {
+			if _, err := h.Store.ClaimClick(context.Background(), id); err != nil {
+				log.Printf("Failed to persist click for link %d: %v", id, err)
+			}
+		}()
+	}
+	return ok, nil
+}
+
+// seedClicks 以数据库中已计入的访问次数初始化计数后重新计数
+func (h *Handler) seedClicks(ctx context.Context, id, maxClicks int64) (bool, error) {
+	link, err := h.Store.GetLink(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := h.Clicks.Seed(ctx, id, link.Clicks, clickCounterTTL); err != nil {
+		return false, err
+	}
+	return h.Clicks.Claim(ctx, id, maxClicks)
+}
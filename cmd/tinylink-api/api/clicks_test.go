@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeCounter 内存中的 ClickCounter，err 非 nil 时所有操作失败
+type fakeCounter struct {
+	mu     sync.Mutex
+	counts map[int64]int64
+	seeds  int
+	err    error
+}
+
+func newFakeCounter() *fakeCounter {
+	return &fakeCounter{counts: make(map[int64]int64)}
+}
+
+func (c *fakeCounter) Claim(ctx context.Context, id, max int64) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return false, c.err
+	}
+	n, ok := c.counts[id]
+	if !ok {
+		return false, storage.ErrCounterMissing
+	}
+	if n >= max {
+		return false, nil
+	}
+	c.counts[id] = n + 1
+	return true, nil
+}
+
+func (c *fakeCounter) Seed(ctx context.Context, id, clicks int64, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.seeds++
+	if _, ok := c.counts[id]; !ok {
+		c.counts[id] = clicks
+	}
+	return nil
+}
+
+// storedClicks 等待异步同步完成后返回数据库中的访问次数
+func storedClicks(t *testing.T, s *testServer, id, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		link, err := s.store.GetLink(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if link.Clicks == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("link %d has %d clicks in the store, want %d", id, link.Clicks, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRedirectMaxClicks(t *testing.T) {
+	s := newTestServer(t)
+	code := s.shorten(t, gin.H{"url": "https://example.com/", "max_clicks": 2})
+
+	for i, want := range []int{http.StatusFound, http.StatusFound, http.StatusGone, http.StatusGone} {
+		if w := s.visit("/"+code, uaDesktop, "10.0.0.1"); w.Code != want {
+			t.Errorf("visit %d = %d, want %d", i+1, w.Code, want)
+		}
+	}
+	storedClicks(t, s, 1, 2)
+}
+
+// TestClaimClickSeed 计数不存在时以数据库中已计入的次数初始化，放行的访问异步同步到数据库
+func TestClaimClickSeed(t *testing.T) {
+	s := newTestServer(t)
+	clicks := newFakeCounter()
+	s.h.Clicks = clicks
+	s.save(t, &storage.Link{ID: 1, LongURL: "https://example.com/", MaxClicks: 3, Clicks: 1})
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false} {
+		if ok, err := s.h.claimClick(ctx, 1, 3); ok != want || err != nil {
+			t.Errorf("claim %d = %v, %v; want %v", i+1, ok, err, want)
+		}
+	}
+	if clicks.seeds != 1 {
+		t.Errorf("seeded %d times, want 1", clicks.seeds)
+	}
+	storedClicks(t, s, 1, 3)
+
+	// 已删除的链接不再放行
+	if ok, err := s.h.claimClick(ctx, 2, 3); ok || err != nil {
+		t.Errorf("claim for a missing link = %v, %v; want false", ok, err)
+	}
+}
+
+// TestClaimClickFallback 计数器不可用时直接在数据库中计数，上限仍然有效
+func TestClaimClickFallback(t *testing.T) {
+	s := newTestServer(t)
+	clicks := newFakeCounter()
+	clicks.err = errors.New("redis down")
+	s.h.Clicks = clicks
+	code := s.shorten(t, gin.H{"url": "https://example.com/", "max_clicks": 2})
+
+	for i, want := range []int{http.StatusFound, http.StatusFound, http.StatusGone} {
+		if w := s.visit("/"+code, uaDesktop, "10.0.0.1"); w.Code != want {
+			t.Errorf("visit %d = %d, want %d", i+1, w.Code, want)
+		}
+	}
+	storedClicks(t, s, 1, 2)
+}
+
+// failingClaimStore 数据库计数失败
+type failingClaimStore struct {
+	storage.LinkStore
+}
+
+func (failingClaimStore) ClaimClick(ctx context.Context, id int64) (bool, error) {
+	return false, errors.New("db down")
+}
+
+func TestRedirectClaimError(t *testing.T) {
+	s := newTestServer(t)
+	code := s.shorten(t, gin.H{"url": "https://example.com/", "max_clicks": 2})
+	s.h.Store = failingClaimStore{s.store}
+	if w := s.visit("/"+code, uaDesktop, "10.0.0.1"); w.Code != http.StatusInternalServerError {
+		t.Errorf("visit = %d, want 500", w.Code)
+	}
+}
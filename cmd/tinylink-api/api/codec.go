@@ -0,0 +1,77 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/yin1895/tinylink/internal/shortcode"
+)
+
+// maxCodeLen 可解码短码的最大长度，62^10 足以覆盖 ID 空间且不会溢出 int64
+const maxCodeLen = 10
+
+// Codec 负责短码与 ID 之间的编解码
+// 未配置密钥时沿用 toBase62(id) 的顺序短码；配置密钥后先做 Feistel 置换，
+// 短码固定为 length 位且不可按顺序枚举
+type Codec struct {
+	perm        *shortcode.Feistel
+	length      int
+	legacyMaxID int64 // 启用置换前发放的最大 ID，不超过它的 ID 仍可通过顺序短码访问
+}
+
+// NewCodec 创建编解码器，key 为空表示不启用置换，length 为置换后短码的固定长度
+// 置换定义域为 [0, 62^length)；legacyMaxID 大于 0 时，ID 不超过它的链接仍接受启用置换前的顺序短码
+func NewCodec(key string, length int, legacyMaxID int64) *Codec {
+	if key == "" {
+		return &Codec{}
+	}
+	limit := uint64(1)
+	for i := 0; i < length; i++ {
+		limit *= uint64(len(alphabet))
+	}
+	return &Codec{perm: shortcode.NewFeistel([]byte(key), limit), length: length, legacyMaxID: legacyMaxID}
+}
+
+// Encode 将 ID 编码为短码
+// 启用置换时先置换再编码，并左补 '0' 到固定长度；
+// 超出置换定义域的 ID 退回顺序编码 (长度必然大于 length，不会与置换短码混淆)
+func (c *Codec) Encode(id int64) string {
+	if c.perm == nil || uint64(id) >= c.perm.Limit() {
+		return toBase62(id)
+	}
+	code := toBase62(int64(c.perm.Permute(uint64(id))))
+	return strings.Repeat("0", c.length-len(code)) + code
+}
+
+// Decode 是 Encode 的逆运算，只接受 Encode 产生的规范短码 (如不接受带前导 '0' 的顺序短码)
+// 启用置换后，顺序短码只对不超过 legacyMaxID 的 ID 有效，其余短码无法按顺序枚举
+func (c *Codec) Decode(code string) (int64, bool) {
+	if !isBase62(code) {
+		return 0, false
+	}
+	n := fromBase62(code)
+	if c.perm == nil {
+		return n, toBase62(n) == code
+	}
+	if len(code) == c.length {
+		id := int64(c.perm.Invert(uint64(n)))
+		return id, c.Encode(id) == code
+	}
+	// 顺序短码：超出置换定义域的 ID，或启用置换前发放的 ID
+	if toBase62(n) != code || (uint64(n) < c.perm.Limit() && n > c.legacyMaxID) {
+		return 0, false
+	}
+	return n, true
+}
+
+// isBase62 判断短码能否通过 fromBase62 解码为 ID
+func isBase62(str string) bool {
+	if str == "" || len(str) > maxCodeLen {
+		return false
+	}
+	for _, char := range str {
+		if !strings.ContainsRune(alphabet, char) {
+			return false
+		}
+	}
+	return true
+}
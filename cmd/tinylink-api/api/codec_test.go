@@ -0,0 +1,71 @@
+package api
+
+import "testing"
+
+func TestCodecRoundTrip(t *testing.T) {
+	codecs := map[string]*Codec{
+		"sequential": NewCodec("", 7, 0),
+		"keyed":      NewCodec("secret", 7, 0),
+		"legacy":     NewCodec("secret", 7, 1000),
+	}
+	ids := []int64{1, 2, 61, 62, 1000, 1001, 123456789, 3521614606207, 3521614606208, 1 << 50}
+	for name, c := range codecs {
+		for _, id := range ids {
+			code := c.Encode(id)
+			got, ok := c.Decode(code)
+			if !ok || got != id {
+				t.Errorf("%s: Decode(Encode(%d) = %q) = %d, %v", name, id, code, got, ok)
+			}
+		}
+	}
+}
+
+func TestCodecKeyedFixedLength(t *testing.T) {
+	c := NewCodec("secret", 7, 0)
+	for _, id := range []int64{1, 2, 3, 1000, 3521614606207} {
+		if code := c.Encode(id); len(code) != 7 {
+			t.Errorf("Encode(%d) = %q, want 7 characters", id, code)
+		}
+	}
+	// 超出置换定义域的 ID 退回顺序编码，长度大于 7
+	if code := c.Encode(3521614606208); len(code) <= 7 {
+		t.Errorf("Encode(62^7) = %q, want more than 7 characters", code)
+	}
+}
+
+func TestCodecRejectsNonCanonical(t *testing.T) {
+	keyed := NewCodec("secret", 7, 0)
+	legacy := NewCodec("secret", 7, 1000)
+	sequential := NewCodec("", 7, 0)
+
+	tests := []struct {
+		name string
+		c    *Codec
+		code string
+		ok   bool
+		id   int64
+	}{
+		{"keyed rejects sequential 1", keyed, "1", false, 0},
+		{"keyed rejects sequential 2", keyed, "2", false, 0},
+		{"keyed rejects sequential 3", keyed, "3", false, 0},
+		{"keyed rejects sequential 1000", keyed, "g8", false, 0},
+		{"keyed rejects 6 characters", keyed, "000001", false, 0},
+		{"keyed rejects 8 characters in domain", keyed, "0000000g", false, 0},
+		{"keyed accepts sequential beyond domain", keyed, toBase62(3521614606208), true, 3521614606208},
+		{"legacy accepts sequential 1000", legacy, "g8", true, 1000},
+		{"legacy accepts sequential 1", legacy, "1", true, 1},
+		{"legacy rejects sequential 1001", legacy, "g9", false, 0},
+		{"legacy rejects leading zero", legacy, "0g8", false, 0},
+		{"sequential accepts 1000", sequential, "g8", true, 1000},
+		{"sequential rejects leading zero", sequential, "0g8", false, 0},
+		{"rejects empty", keyed, "", false, 0},
+		{"rejects non base62", keyed, "abc-def", false, 0},
+		{"rejects too long", sequential, "12345678901", false, 0},
+	}
+	for _, tt := range tests {
+		id, ok := tt.c.Decode(tt.code)
+		if ok != tt.ok || (ok && id != tt.id) {
+			t.Errorf("%s: Decode(%q) = %d, %v; want %d, %v", tt.name, tt.code, id, ok, tt.id, tt.ok)
+		}
+	}
+}
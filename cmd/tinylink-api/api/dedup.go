@@ -0,0 +1,34 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/yin1895/tinylink/internal/storage"
+)
+
+// hashURL 计算规范化长链接的 SHA-256，作为去重键
+func hashURL(longURL string) string {
+	sum := sha256.Sum256([]byte(longURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// dedupHash 返回新链接参与去重时使用的哈希
+// 仅在开启去重且链接没有别名、过期时间、密码等个性化设置 (custom 为 false) 时去重，其余情况返回空字符串
+func (h *Handler) dedupHash(longURL string, custom bool) string {
+	if !h.Dedup || custom {
+		return ""
+	}
+	return hashURL(longURL)
+}
+
+// findDuplicate 查找域名下该用户已有的去重链接，不存在时返回 nil
+func (h *Handler) findDuplicate(ctx context.Context, domain, ownerID, hash string) (*storage.Link, error) {
+	link, err := h.Store.GetLinkByURLHash(ctx, domain, ownerID, hash)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	return link, err
+}
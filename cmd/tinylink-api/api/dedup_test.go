@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestShortenDedup(t *testing.T) {
+	s := newTestServer(t)
+	s.h.Dedup = true
+	s.h.Quotas = NewQuotas(s.store, nil, Plan{MonthlyLinks: 5}) // 1 个去重链接 + 4 个不同的链接
+
+	code := s.shorten(t, gin.H{"url": "https://example.com/a"})
+	// 规范化后相同的长链接复用已有短码，且不占用额度
+	for range 5 {
+		if got := s.shorten(t, gin.H{"url": "HTTPS://Example.com:443/a"}); got != code {
+			t.Errorf("duplicate URL got code %q, want %q", got, code)
+		}
+	}
+
+	tests := []struct {
+		name string
+		body gin.H
+	}{
+		{"other URL", gin.H{"url": "https://example.com/b"}},
+		{"other domain", gin.H{"url": "https://example.com/a", "domain": "go.brand.test"}},
+		{"with expiry", gin.H{"url": "https://example.com/a", "ttl_seconds": 60}},
+		{"with alias", gin.H{"url": "https://example.com/a", "alias": "promo"}},
+	}
+	for _, tt := range tests {
+		if got := s.shorten(t, tt.body); got == code {
+			t.Errorf("%s: reused code %q", tt.name, code)
+		}
+	}
+	// 带个性化设置的链接不作为去重目标
+	if got := s.shorten(t, gin.H{"url": "https://example.com/a"}); got != code {
+		t.Errorf("after custom links: got code %q, want %q", got, code)
+	}
+}
+
+func TestShortenDedupDisabled(t *testing.T) {
+	s := newTestServer(t)
+	if a, b := s.shorten(t, gin.H{"url": "https://example.com/"}), s.shorten(t, gin.H{"url": "https://example.com/"}); a == b {
+		t.Errorf("dedup disabled: both requests got code %q", a)
+	}
+}
+
+// TestShortenDedupPerOwner 去重只在同一持有者的链接之间进行
+func TestShortenDedupPerOwner(t *testing.T) {
+	s := newTestServer(t, apiKeyAuth(t, "alice", "bob"))
+	s.h.Dedup = true
+
+	codes := make(map[string]string)
+	for _, owner := range []string{"alice", "bob", "alice"} {
+		w := s.doAs(t, owner, http.MethodPost, "/shorten", gin.H{"url": "https://example.com/"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: POST /shorten = %d %s", owner, w.Code, w.Body)
+		}
+		shortURL, _ := decodeBody(t, w)["short_url"].(string)
+		code := strings.TrimPrefix(shortURL, testBaseURL+"/")
+		if prev, ok := codes[owner]; ok && prev != code {
+			t.Errorf("%s got code %q, want the earlier %q", owner, code, prev)
+		}
+		codes[owner] = code
+	}
+	if codes["alice"] == codes["bob"] {
+		t.Errorf("alice and bob share code %q", codes["alice"])
+	}
+}
+
+// TestUpdateLinkLeavesDedup 修改目标地址后的链接不再参与去重
+func TestUpdateLinkLeavesDedup(t *testing.T) {
+	s := newTestServer(t)
+	s.h.Dedup = true
+	code := s.shorten(t, gin.H{"url": "https://example.com/a"})
+	if w := s.do(t, http.MethodPatch, "/api/links/"+code, gin.H{"url": "https://example.com/a?v=2"}); w.Code != http.StatusOK {
+		t.Fatalf("PATCH: %d %s", w.Code, w.Body)
+	}
+	for _, u := range []string{"https://example.com/a", "https://example.com/a?v=2"} {
+		if got := s.shorten(t, gin.H{"url": u}); got == code {
+			t.Errorf("shorten %s reused the updated link %q", u, code)
+		}
+	}
+}
+
+// racingStore 第一次按哈希查询时返回未找到，模拟并发请求在查询之后抢先保存了相同长链接
+type racingStore struct {
+	storage.LinkStore
+	raced bool
+}
+
+func (s *racingStore) GetLinkByURLHash(ctx context.Context, domain, ownerID, hash string) (*storage.Link, error) {
+	if !s.raced {
+		s.raced = true
+		return nil, storage.ErrNotFound
+	}
+	return s.LinkStore.GetLinkByURLHash(ctx, domain, ownerID, hash)
+}
+
+func TestShortenDedupRace(t *testing.T) {
+	s := newTestServer(t)
+	s.h.Dedup = true
+	s.h.Quotas = NewQuotas(s.store, nil, Plan{MonthlyLinks: 2})
+	code := s.shorten(t, gin.H{"url": "https://example.com/"})
+
+	s.h.Store = &racingStore{LinkStore: s.store}
+	if got := s.shorten(t, gin.H{"url": "https://example.com/"}); got != code {
+		t.Errorf("racing duplicate got code %q, want %q", got, code)
+	}
+	// 保存失败的请求退还了额度
+	s.shorten(t, gin.H{"url": "https://example.com/other"})
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// 设备平台，作为链接 targets 的键
+const (
+	platformIOS     = "ios"
+	platformAndroid = "android"
+	platformMobile  = "mobile" // 其他手机平台，也是 iOS / Android 未单独设置时的候选
+	platformDesktop = "desktop"
+)
+
+// platforms 允许在 targets 中使用的平台
+var platforms = []string{platformIOS, platformAndroid, platformMobile, platformDesktop}
+
+// botMarkers 爬虫与链接预览程序的 UA 特征，这类请求统一跳转到默认地址
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "preview"}
+
+// mobileMarkers iOS / Android 之外的移动设备 UA 特征
+var mobileMarkers = []string{"mobile", "windows phone", "blackberry", "bb10", "opera mini", "kaios"}
+
+// classifyPlatform 根据 User-Agent 判断设备平台，无法判断 (空 UA、爬虫) 时返回空字符串
+// iPadOS 13 起 Safari 默认使用桌面版 UA，此类 iPad 会被识别为 desktop
+func classifyPlatform(ua string) string {
+	ua = strings.ToLower(ua)
+	switch {
+	case ua == "" || containsAny(ua, botMarkers):
+		return ""
+	case containsAny(ua, []string{"iphone", "ipad", "ipod"}):
+		return platformIOS
+	case strings.Contains(ua, "android"): // 须在 linux 之前判断，Android UA 中同样包含 Linux
+		return platformAndroid
+	case containsAny(ua, mobileMarkers):
+		return platformMobile
+	default:
+		return platformDesktop
+	}
+}
+
+func containsAny(s string, subs []string) bool {
+	return slices.ContainsFunc(subs, func(sub string) bool { return strings.Contains(s, sub) })
+}
+
+// selectTarget 返回平台对应的目标地址
+// iOS / Android 未单独设置时使用 mobile 的地址，仍未匹配时使用默认地址
+func selectTarget(targets map[string]string, platform, fallback string) string {
+	if u, ok := targets[platform]; ok {
+		return u
+	}
+	if platform == platformIOS || platform == platformAndroid {
+		if u, ok := targets[platformMobile]; ok {
+			return u
+		}
+	}
+	return fallback
+}
+
+// checkTargets 校验 targets 的平台与目标地址，返回规范化后的副本，为空时返回 nil
+func (h *Handler) checkTargets(targets map[string]string) (map[string]string, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	checked := make(map[string]string, len(targets))
+	for _, platform := range slices.Sorted(maps.Keys(targets)) {
+		target := targets[platform]
+		if !slices.Contains(platforms, platform) {
+			return nil, fmt.Errorf("targets: unknown platform %q, must be one of %s", platform, strings.Join(platforms, ", "))
+		}
+		u, err := h.checkLongURL(target)
+		if err != nil {
+			return nil, fmt.Errorf("targets.%s: %w", platform, err)
+		}
+		checked[platform] = u
+	}
+	return checked, nil
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClassifyPlatform(t *testing.T) {
+	tests := []struct {
+		name, ua, want string
+	}{
+		{"empty", "", ""},
+		{"iPhone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1", platformIOS},
+		{"iPad", "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1", platformIOS},
+		{"iPod", "Mozilla/5.0 (iPod touch; CPU iPhone OS 12_5 like Mac OS X)", platformIOS},
+		{"Android phone", "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Mobile Safari/537.36", platformAndroid},
+		{"Android tablet", "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36", platformAndroid},
+		{"Opera Mini", "Opera/9.80 (J2ME/MIDP; Opera Mini/9.80 (S60; SymbOS; Opera Mobi/23.348; U; en) Presto/2.5.25 Version/10.54", platformMobile},
+		{"KaiOS", "Mozilla/5.0 (Mobile; Nokia 8110 4G; rv:48.0) Gecko/48.0 Firefox/48.0 KAIOS/2.5", platformMobile},
+		{"BlackBerry", "Mozilla/5.0 (BB10; Touch) AppleWebKit/537.10+ (KHTML, like Gecko) Version/10.0.9.2372 Mobile Safari/537.10+", platformMobile},
+		{"Windows Chrome", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36", platformDesktop},
+		{"macOS Safari (also iPadOS 13+)", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15", platformDesktop},
+		{"Linux Firefox", "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0", platformDesktop},
+		{"curl", "curl/8.5.0", platformDesktop},
+		{"Googlebot", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", ""},
+		{"Googlebot smartphone", "Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Mobile Safari/537.36 (compatible; Googlebot/2.1)", ""},
+		{"Facebook preview", "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", ""},
+		{"Slack preview", "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", ""},
+		{"upper case", "MOZILLA/5.0 (IPHONE)", platformIOS},
+	}
+	for _, tt := range tests {
+		if got := classifyPlatform(tt.ua); got != tt.want {
+			t.Errorf("%s: classifyPlatform = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSelectTarget(t *testing.T) {
+	const fallback = "https://example.com/"
+	all := map[string]string{
+		platformIOS:     "https://apps.apple.com/app",
+		platformAndroid: "https://play.google.com/app",
+		platformMobile:  "https://m.example.com/",
+		platformDesktop: "https://www.example.com/",
+	}
+	mobileOnly := map[string]string{platformMobile: "https://m.example.com/"}
+	iosOnly := map[string]string{platformIOS: "https://apps.apple.com/app"}
+
+	tests := []struct {
+		name     string
+		targets  map[string]string
+		platform string
+		want     string
+	}{
+		{"exact ios", all, platformIOS, all[platformIOS]},
+		{"exact android", all, platformAndroid, all[platformAndroid]},
+		{"exact mobile", all, platformMobile, all[platformMobile]},
+		{"exact desktop", all, platformDesktop, all[platformDesktop]},
+		{"unknown platform", all, "", fallback},
+		{"ios falls back to mobile", mobileOnly, platformIOS, mobileOnly[platformMobile]},
+		{"android falls back to mobile", mobileOnly, platformAndroid, mobileOnly[platformMobile]},
+		{"desktop does not use mobile", mobileOnly, platformDesktop, fallback},
+		{"android does not use ios", iosOnly, platformAndroid, fallback},
+		{"mobile does not use ios", iosOnly, platformMobile, fallback},
+		{"no targets", nil, platformIOS, fallback},
+	}
+	for _, tt := range tests {
+		if got := selectTarget(tt.targets, tt.platform, fallback); got != tt.want {
+			t.Errorf("%s: selectTarget = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckTargets(t *testing.T) {
+	h := newTestHandler(t)
+	tests := []struct {
+		name    string
+		targets map[string]string
+		want    map[string]string
+		err     string
+	}{
+		{"empty", nil, nil, ""},
+		{"normalised", map[string]string{"ios": "HTTPS://Apps.Apple.com", "desktop": "http://example.com:80/x"},
+			map[string]string{"ios": "https://apps.apple.com/", "desktop": "http://example.com/x"}, ""},
+		{"unknown platform", map[string]string{"windows": "https://example.com"}, nil, `unknown platform "windows"`},
+		{"platform is case sensitive", map[string]string{"iOS": "https://example.com"}, nil, `unknown platform "iOS"`},
+		{"invalid URL", map[string]string{"android": "javascript:alert(1)"}, nil, "targets.android:"},
+		{"points to this service", map[string]string{"mobile": "http://short.test/abc"}, nil, "targets.mobile:"},
+	}
+	for _, tt := range tests {
+		got, err := h.checkTargets(tt.targets)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: checkTargets error = %v, want containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: checkTargets: %v", tt.name, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: checkTargets = %v, want %v", tt.name, got, tt.want)
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("%s: checkTargets[%s] = %q, want %q", tt.name, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestRedirectTargets(t *testing.T) {
+	s := newTestServer(t)
+	code := s.shorten(t, gin.H{
+		"url":     "https://example.com/",
+		"targets": gin.H{"ios": "https://apps.apple.com/app", "mobile": "https://m.example.com/"},
+	})
+
+	tests := []struct {
+		name, ua, want, platform string
+	}{
+		{"ios", uaIPhone, "https://apps.apple.com/app", platformIOS},
+		{"android falls back to mobile", uaAndroid, "https://m.example.com/", platformAndroid},
+		{"desktop gets long URL", uaDesktop, "https://example.com/", platformDesktop},
+		{"bot gets long URL", "Googlebot/2.1", "https://example.com/", ""},
+	}
+	for _, tt := range tests {
+		w := s.visit("/"+code, tt.ua, "10.0.0.1")
+		if w.Code != http.StatusFound || w.Header().Get("Location") != tt.want || w.Header().Get("Vary") != "User-Agent" {
+			t.Errorf("%s: %d %q %v, want 302 %q varying by User-Agent", tt.name, w.Code, w.Header().Get("Location"), w.Header(), tt.want)
+			continue
+		}
+		// 事件中的 long_url 始终是链接保存的地址，destination 为实际跳转的地址
+		ev := s.event(t)
+		if ev.Platform != tt.platform || ev.LongURL != "https://example.com/" || ev.Destination != tt.want {
+			t.Errorf("%s: event = %+v", tt.name, ev)
+		}
+	}
+
+	// 取消平台目标后所有设备都跳转到长链接
+	if w := s.do(t, http.MethodPatch, "/api/links/"+code, gin.H{"targets": nil}); w.Code != http.StatusOK {
+		t.Fatalf("PATCH = %d %s", w.Code, w.Body)
+	}
+	if w := s.visit("/"+code, uaIPhone, "10.0.0.1"); w.Header().Get("Location") != "https://example.com/" || w.Header().Get("Vary") != "" {
+		t.Errorf("after removing targets: %q %v", w.Header().Get("Location"), w.Header())
+	}
+}
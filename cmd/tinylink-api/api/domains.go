@@ -0,0 +1,117 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+var errUnknownDomain = errors.New("unknown domain")
+
+// Domains 短链接对外访问的域名：一个默认域名加若干品牌域名
+// 链接按域名隔离存储，默认域名在存储中以空字符串表示，
+// 因此修改默认访问地址不影响已生成的链接
+type Domains struct {
+	defaultBase string
+	defaultHost string
+	branded     map[string]string // host -> 访问地址
+}
+
+// NewDomains 根据默认访问地址和品牌域名访问地址创建 Domains，地址末尾不含 '/'
+func NewDomains(baseURL string, brandedURLs []string) (*Domains, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	d := &Domains{
+		defaultBase: baseURL,
+		defaultHost: hostKey(u),
+		branded:     make(map[string]string, len(brandedURLs)),
+	}
+	for _, b := range brandedURLs {
+		u, err := url.Parse(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid domain URL %q: %w", b, err)
+		}
+		host := hostKey(u)
+		if _, dup := d.branded[host]; dup || host == d.defaultHost {
+			return nil, fmt.Errorf("duplicate domain %q", host)
+		}
+		d.branded[host] = b
+	}
+	return d, nil
+}
+
+// Resolve 根据请求的 Host 头确定链接所属域名，非品牌域名一律视为默认域名
+func (d *Domains) Resolve(host string) string {
+	host = strings.ToLower(host)
+	if _, ok := d.branded[host]; ok {
+		return host
+	}
+	// 品牌域名未配置端口时，忽略请求中的端口
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		if _, ok := d.branded[h]; ok {
+			return h
+		}
+	}
+	return ""
+}
+
+// Lookup 校验请求中指定的域名，返回其在存储中的表示
+// 空字符串或默认域名的 host 表示默认域名
+func (d *Domains) Lookup(name string) (string, error) {
+	name = strings.ToLower(name)
+	if name == "" || name == d.defaultHost {
+		return "", nil
+	}
+	if _, ok := d.branded[name]; ok {
+		return name, nil
+	}
+	return "", errUnknownDomain
+}
+
+// Owns 判断 host (小写、不含默认端口) 是否为本服务的某个域名
+func (d *Domains) Owns(host string) bool {
+	if host == d.defaultHost {
+		return true
+	}
+	_, ok := d.branded[host]
+	return ok
+}
+
+// Name 返回域名对外展示的 host
+func (d *Domains) Name(domain string) string {
+	if domain == "" {
+		return d.defaultHost
+	}
+	return domain
+}
+
+// ShortURL 拼接域名下的完整短链接
+func (d *Domains) ShortURL(domain, code string) string {
+	base, ok := d.branded[domain]
+	if !ok {
+		base = d.defaultBase
+	}
+	return base + "/" + code
+}
+
+// hostKey 返回访问地址的 host，转为小写并省略协议默认端口
+func hostKey(u *url.URL) string {
+	host := strings.ToLower(u.Host)
+	if port := u.Port(); port != "" && port == defaultPorts[u.Scheme] {
+		host = strings.TrimSuffix(host, ":"+port)
+	}
+	return host
+}
+
+// scopedKey 返回缓存和布隆过滤器中使用的短码 key
+// 默认域名直接使用短码，与引入多域名之前的数据兼容
+func scopedKey(domain, code string) string {
+	if domain == "" {
+		return code
+	}
+	return domain + "/" + code
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewDomains(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		branded []string
+		ok      bool
+	}{
+		{"default only", "https://tiny.link", nil, true},
+		{"branded", "https://tiny.link", []string{"https://go.brand.test", "http://b.test:8080"}, true},
+		{"duplicate branded", "https://tiny.link", []string{"https://go.brand.test", "http://GO.brand.test"}, false},
+		{"branded same as default", "https://tiny.link", []string{"https://tiny.link:443"}, false},
+		{"invalid branded URL", "https://tiny.link", []string{"://bad"}, false},
+	}
+	for _, tt := range tests {
+		if _, err := NewDomains(tt.base, tt.branded); (err == nil) != tt.ok {
+			t.Errorf("%s: NewDomains = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestDomains(t *testing.T) {
+	d, err := NewDomains("https://tiny.link:443", []string{"https://go.brand.test", "http://b.test:8080"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolve := map[string]string{
+		"tiny.link":        "",
+		"unknown.test":     "",
+		"GO.Brand.Test":    "go.brand.test",
+		"go.brand.test:80": "go.brand.test", // 品牌域名未配置端口时忽略请求中的端口
+		"b.test:8080":      "b.test:8080",
+		"b.test":           "",
+	}
+	for host, want := range resolve {
+		if got := d.Resolve(host); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", host, got, want)
+		}
+	}
+
+	lookup := []struct {
+		name, want string
+		ok         bool
+	}{
+		{"", "", true},
+		{"tiny.link", "", true},
+		{"Go.Brand.Test", "go.brand.test", true},
+		{"other.test", "", false},
+	}
+	for _, tt := range lookup {
+		if got, err := d.Lookup(tt.name); got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("Lookup(%q) = %q, %v; want %q, ok %v", tt.name, got, err, tt.want, tt.ok)
+		}
+	}
+
+	for host, want := range map[string]bool{"tiny.link": true, "go.brand.test": true, "b.test:8080": true, "b.test": false, "evil.test": false} {
+		if got := d.Owns(host); got != want {
+			t.Errorf("Owns(%q) = %v, want %v", host, got, want)
+		}
+	}
+
+	if got := d.Name(""); got != "tiny.link" {
+		t.Errorf("Name(default) = %q, want tiny.link", got)
+	}
+	if got := d.ShortURL("", "abc"); got != "https://tiny.link:443/abc" {
+		t.Errorf("ShortURL(default) = %q", got)
+	}
+	if got := d.ShortURL("go.brand.test", "abc"); got != "https://go.brand.test/abc" {
+		t.Errorf("ShortURL(branded) = %q", got)
+	}
+	if scopedKey("", "abc") != "abc" || scopedKey("go.brand.test", "abc") != "go.brand.test/abc" {
+		t.Error("scopedKey must keep default-domain keys unprefixed")
+	}
+}
+
+// TestDomainIsolation 同一短码在不同域名下互不干扰，链接只能通过所属域名访问
+func TestDomainIsolation(t *testing.T) {
+	s := newTestServer(t)
+	s.shorten(t, gin.H{"url": "https://example.com/default", "alias": "promo"})
+	s.shorten(t, gin.H{"url": "https://example.com/brand", "alias": "promo", "domain": "go.brand.test"})
+	code := s.shorten(t, gin.H{"url": "https://example.com/id", "domain": "go.brand.test"})
+
+	tests := []struct {
+		path     string
+		want     int
+		location string
+	}{
+		{"/promo", http.StatusFound, "https://example.com/default"},
+		{testBrandURL + "/promo", http.StatusFound, "https://example.com/brand"},
+		{testBrandURL + "/" + code, http.StatusFound, "https://example.com/id"},
+		{"/" + code, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := s.visit(tt.path, uaDesktop, "10.0.0.1")
+		if w.Code != tt.want || w.Header().Get("Location") != tt.location {
+			t.Errorf("visit %s = %d %q, want %d %q", tt.path, w.Code, w.Header().Get("Location"), tt.want, tt.location)
+		}
+	}
+
+	// 点击事件带上所属域名，分析服务据此区分不同域名下的同名短码
+	for range 3 {
+		s.event(t)
+	}
+	s.visit(testBrandURL+"/promo", uaDesktop, "10.0.0.1")
+	if ev := s.event(t); ev.Domain != "go.brand.test" || ev.ShortURL != "promo" {
+		t.Errorf("event = %+v, want domain go.brand.test and short_url promo", ev)
+	}
+}
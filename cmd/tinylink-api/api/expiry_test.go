@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResolveExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 500, time.UTC)
+	local := time.FixedZone("UTC+8", 8*3600)
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		ttl       int64
+		want      *time.Time
+		err       string
+	}{
+		{"neither", nil, 0, nil, ""},
+		{"ttl", nil, 3600, ptr(time.Date(2026, 3, 1, 13, 0, 0, 0, time.UTC)), ""},
+		{"expires_at converted to UTC", ptr(time.Date(2026, 3, 2, 8, 0, 0, 900, local)), 0, ptr(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)), ""},
+		{"both", ptr(now.Add(time.Hour)), 60, nil, "mutually exclusive"},
+		{"negative ttl", nil, -1, nil, "ttl_seconds must be positive"},
+		{"expires_at now", ptr(now), 0, nil, "must be in the future"},
+		{"expires_at in the past", ptr(now.Add(-time.Hour)), 0, nil, "must be in the future"},
+	}
+	for _, tt := range tests {
+		got, err := resolveExpiry(tt.expiresAt, tt.ttl, now)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error = %v, want containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || got != nil && !got.Equal(*tt.want) || got != nil && got.Location() != time.UTC {
+			t.Errorf("%s: resolveExpiry = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// ttlCache 记录每个键最近一次写入时的有效期
+type ttlCache struct {
+	storage.Cache
+	ttls map[string]time.Duration
+}
+
+func (c *ttlCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	c.ttls[key] = ttl
+	return c.Cache.Set(ctx, key, value, ttl)
+}
+
+func TestShortenExpiry(t *testing.T) {
+	s := newTestServer(t)
+	w := s.do(t, http.MethodPost, "/shorten", gin.H{"url": "https://example.com/", "ttl_seconds": 3600})
+	if w.Code != http.StatusOK {
+		t.Fatalf("ttl_seconds: %d %s", w.Code, w.Body)
+	}
+	expiresAt, err := time.Parse(time.RFC3339, decodeBody(t, w)["expires_at"].(string))
+	if err != nil || expiresAt.Sub(time.Now()) > time.Hour || expiresAt.Sub(time.Now()) < 59*time.Minute {
+		t.Errorf("expires_at = %v (%v), want about an hour from now", expiresAt, err)
+	}
+
+	for _, body := range []gin.H{
+		{"url": "https://example.com/", "ttl_seconds": 60, "expires_at": time.Now().Add(time.Hour)},
+		{"url": "https://example.com/", "expires_at": time.Now().Add(-time.Hour)},
+		{"url": "https://example.com/", "ttl_seconds": -5},
+	} {
+		if w := s.do(t, http.MethodPost, "/shorten", body); w.Code != http.StatusBadRequest {
+			t.Errorf("POST /shorten %v = %d, want 400", body, w.Code)
+		}
+	}
+}
+
+// TestRedirectExpiry 过期链接返回 410；未过期链接的缓存有效期截止到过期时刻
+func TestRedirectExpiry(t *testing.T) {
+	s := newTestServer(t)
+	cache := &ttlCache{Cache: s.h.Cache, ttls: make(map[string]time.Duration)}
+	s.h.Cache = cache
+	now := time.Now().UTC()
+	s.save(t,
+		&storage.Link{ID: 1, LongURL: "https://example.com/expired", ExpiresAt: ptr(now.Add(-time.Second))},
+		&storage.Link{ID: 2, LongURL: "https://example.com/later", ExpiresAt: ptr(now.Add(time.Hour))},
+		&storage.Link{ID: 3, LongURL: "https://example.com/forever"},
+	)
+
+	if w := s.visit("/1", uaDesktop, "10.0.0.1"); w.Code != http.StatusGone {
+		t.Errorf("expired link: %d, want 410", w.Code)
+	}
+	if _, cached := cache.ttls["1"]; cached {
+		t.Error("expired link was cached")
+	}
+
+	if w := s.visit("/2", uaDesktop, "10.0.0.1"); w.Code != http.StatusFound {
+		t.Errorf("unexpired link: %d, want 302", w.Code)
+	}
+	if ttl := cache.ttls["2"]; ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("cache TTL of a link expiring in an hour = %v", ttl)
+	}
+
+	if w := s.visit("/3", uaDesktop, "10.0.0.1"); w.Code != http.StatusFound {
+		t.Errorf("link without expiry: %d, want 302", w.Code)
+	}
+	if ttl, ok := cache.ttls["3"]; !ok || ttl != 0 {
+		t.Errorf("cache TTL of a link without expiry = %v (cached %v), want 0", ttl, ok)
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// CountryResolver 将客户端 IP 解析为国家代码 (ISO 3166-1，大写)，无法解析时返回空字符串
+type CountryResolver interface {
+	Country(ip string) string
+}
+
+// checkGeoTargets 校验 geo_targets 的国家代码与目标地址，国家代码统一为大写，为空时返回 nil
+func (h *Handler) checkGeoTargets(targets map[string]string) (map[string]string, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	checked := make(map[string]string, len(targets))
+	for _, country := range slices.Sorted(maps.Keys(targets)) {
+		code := strings.ToUpper(country)
+		if !isCountryCode(code) {
+			return nil, fmt.Errorf("geo_targets: %q is not an ISO 3166-1 alpha-2 country code", country)
+		}
+		if _, dup := checked[code]; dup {
+			return nil, fmt.Errorf("geo_targets: duplicate country %q", code)
+		}
+		u, err := h.checkLongURL(targets[country])
+		if err != nil {
+			return nil, fmt.Errorf("geo_targets.%s: %w", code, err)
+		}
+		checked[code] = u
+	}
+	return checked, nil
+}
+
+func isCountryCode(s string) bool {
+	return len(s) == 2 && s[0] >= 'A' && s[0] <= 'Z' && s[1] >= 'A' && s[1] <= 'Z'
+}
+
+// country 解析客户端所在国家，未配置 GeoIP 数据库时返回空字符串
+func (h *Handler) country(ip string) string {
+	if h.Geo == nil {
+		return ""
+	}
+	return h.Geo.Country(ip)
+}
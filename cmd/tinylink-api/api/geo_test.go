@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// staticCountries 按固定表解析国家的 CountryResolver
+type staticCountries map[string]string
+
+func (s staticCountries) Country(ip string) string { return s[ip] }
+
+func TestIsCountryCode(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"DE", true},
+		{"US", true},
+		{"de", false},
+		{"D", false},
+		{"DEU", false},
+		{"D1", false},
+		{"", false},
+		{"ÄÖ", false},
+	}
+	for _, tt := range tests {
+		if got := isCountryCode(tt.s); got != tt.want {
+			t.Errorf("isCountryCode(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestCheckGeoTargets(t *testing.T) {
+	h := newTestHandler(t)
+	tests := []struct {
+		name    string
+		targets map[string]string
+		want    map[string]string
+		err     string
+	}{
+		{"empty", map[string]string{}, nil, ""},
+		{"upper cased and normalised", map[string]string{"de": "https://Example.de", "US": "https://example.com/us"},
+			map[string]string{"DE": "https://example.de/", "US": "https://example.com/us"}, ""},
+		{"duplicate after upper casing", map[string]string{"de": "https://a.example", "DE": "https://b.example"}, nil, `duplicate country "DE"`},
+		{"not a country code", map[string]string{"DEU": "https://example.de"}, nil, `"DEU" is not an ISO 3166-1 alpha-2`},
+		{"invalid URL", map[string]string{"FR": "ftp://example.fr"}, nil, "geo_targets.FR:"},
+	}
+	for _, tt := range tests {
+		got, err := h.checkGeoTargets(tt.targets)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: checkGeoTargets error = %v, want containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: checkGeoTargets: %v", tt.name, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: checkGeoTargets = %v, want %v", tt.name, got, tt.want)
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("%s: checkGeoTargets[%s] = %q, want %q", tt.name, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestHandlerCountry(t *testing.T) {
+	h := newTestHandler(t)
+	if got := h.country("81.2.69.160"); got != "" {
+		t.Errorf("country without GeoIP = %q, want empty", got)
+	}
+	h.Geo = staticCountries{"81.2.69.160": "GB"}
+	if got := h.country("81.2.69.160"); got != "GB" {
+		t.Errorf("country = %q, want GB", got)
+	}
+	if got := h.country("10.0.0.1"); got != "" {
+		t.Errorf("country of unknown IP = %q, want empty", got)
+	}
+}
+
+// TestRedirectGeoTargets 国家目标优先于设备平台目标，点击事件记录解析出的国家
+func TestRedirectGeoTargets(t *testing.T) {
+	s := newTestServer(t)
+	s.h.Geo = staticCountries{"81.2.69.160": "GB", "5.9.0.1": "DE"}
+	code := s.shorten(t, gin.H{
+		"url":         "https://example.com/",
+		"targets":     gin.H{"ios": "https://apps.apple.com/app"},
+		"geo_targets": gin.H{"de": "https://example.de/"},
+	})
+
+	tests := []struct {
+		name, ua, ip, want, country string
+	}{
+		{"country wins over platform", uaIPhone, "5.9.0.1", "https://example.de/", "DE"},
+		{"platform when country has no target", uaIPhone, "81.2.69.160", "https://apps.apple.com/app", "GB"},
+		{"long URL when nothing matches", uaDesktop, "81.2.69.160", "https://example.com/", "GB"},
+		{"unknown country", uaDesktop, "10.0.0.1", "https://example.com/", ""},
+	}
+	for _, tt := range tests {
+		w := s.visit("/"+code, tt.ua, tt.ip)
+		if w.Code != http.StatusFound || w.Header().Get("Location") != tt.want {
+			t.Errorf("%s: %d %q, want 302 %q", tt.name, w.Code, w.Header().Get("Location"), tt.want)
+			continue
+		}
+		if ev := s.event(t); ev.Country != tt.country || ev.Destination != tt.want || ev.LongURL != "https://example.com/" {
+			t.Errorf("%s: event = %+v", tt.name, ev)
+		}
+	}
+
+	body := decodeBody(t, s.do(t, http.MethodGet, "/api/links/"+code, nil))
+	if geo, _ := body["geo_targets"].(map[string]any); geo["DE"] != "https://example.de/" {
+		t.Errorf("GET geo_targets = %v", body["geo_targets"])
+	}
+}
@@ -4,27 +4,52 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/yin1895/tinylink/internal/storage"
-	pb "github.com/yin1895/tinylink/pkg/proto"
 
 	"github.com/gin-gonic/gin"
-	"github.com/segmentio/kafka-go"
 )
 
-var IdGenClient pb.IdGeneratorClient
+// Handler 持有处理 HTTP 请求所需的依赖，由 main 组装后注册路由
+type Handler struct {
+	Store   storage.LinkStore    // 链接存储
+	Cache   storage.Cache        // 短码 -> 长链接缓存
+	Bloom   storage.Filter       // 短码布隆过滤器
+	Events  storage.EventSink    // 点击事件
+	IDs     IDAllocator          // ID 分配
+	Codec   *Codec               // 短码编解码
+	Domains *Domains             // 对外访问的域名
+	Dedup   bool                 // 相同长链接复用已有短码
+	Quotas  *Quotas              // 持有者的套餐额度
+	Clicks  storage.ClickCounter // 限次链接的访问计数，nil 时直接在 Store 中计数
+
+	FallbackURL string          // 链接不在生效时间窗口内时跳转的地址，为空时返回 404
+	Geo         CountryResolver // 客户端 IP -> 国家，nil 表示未启用 GeoIP
+	Queries     *QueryTemplates // 持有者的默认查询参数模板
+
+	Limits           storage.RateLimiter // 密码尝试次数限制
+	PasswordAttempts int                 // 同一客户端在 PasswordWindow 内对同一链接的密码尝试次数，0 表示不限
+	PasswordWindow   time.Duration
+}
 
 const alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
 // ClickEvent 定义发送到 Kafka 的数据结构
 type ClickEvent struct {
-	ShortURL  string `json:"short_url"`
-	LongURL   string `json:"long_url"`
-	IP        string `json:"ip"`
-	UserAgent string `json:"user_agent"`
-	Timestamp int64  `json:"timestamp"`
+	ShortURL    string `json:"short_url"`
+	Domain      string `json:"domain"`
+	LongURL     string `json:"long_url"`           // 链接保存的长链接
+	Destination string `json:"destination"`        // 本次跳转的最终地址 (选择目标并合并查询参数后)
+	Platform    string `json:"platform,omitempty"` // 按 User-Agent 识别的设备平台
+	Country     string `json:"country,omitempty"`  // 按 GeoIP 数据库解析的国家代码
+	Variant     string `json:"variant,omitempty"`  // A/B 分流链接分配到的分组
+	IP          string `json:"ip"`
+	UserAgent   string `json:"user_agent"`
+	Timestamp   int64  `json:"timestamp"`
 }
 
 func toBase62(num int64) string {
@@ -55,79 +80,433 @@ func fromBase62(str string) int64 {
 	return result
 }
 
-func ShortenURLHandler(c *gin.Context) {
+// ShortenURL 生成短链接 POST /shorten
+func (h *Handler) ShortenURL(c *gin.Context) {
 	var json struct {
-		URL string `json:"url" binding:"required"`
+		URL        string            `json:"url"`
+		Targets    map[string]string `json:"targets"`
+		GeoTargets map[string]string `json:"geo_targets"`
+		Variants   []storage.Variant `json:"variants"`
+		Query      map[string]string `json:"query_params"`
+		PassQuery  *bool             `json:"pass_query"`
+		Domain     string            `json:"domain"`
+		Alias      string            `json:"alias"`
+		Password   string            `json:"password"`
+		MaxClicks  int64             `json:"max_clicks"`
+		NotBefore  *time.Time        `json:"not_before"`
+		NotAfter   *time.Time        `json:"not_after"`
+		ExpiresAt  *time.Time        `json:"expires_at"`
+		TTLSeconds int64             `json:"ttl_seconds"`
 	}
 	if err := c.ShouldBindJSON(&json); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "URL is required"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(err)})
+		return
+	}
+
+	// url 缺省或为空时由 checkLongURL 返回 "URL is required"
+	longURL, err := h.checkLongURL(json.URL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	targets, err := h.checkTargets(json.Targets)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	geoTargets, err := h.checkGeoTargets(json.GeoTargets)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	variants, err := h.checkVariants(json.Variants)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query, err := checkQueryParams(json.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	domain, err := h.Domains.Lookup(json.Domain)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expiresAt, err := resolveExpiry(json.ExpiresAt, json.TTLSeconds, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 自定义别名：先校验格式与保留字，再检查域名内的冲突
+	if json.Alias != "" {
+		if err := validateAlias(json.Alias); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		taken, err := h.aliasTaken(c.Request.Context(), domain, json.Alias)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check alias"})
+			return
+		}
+		if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": storage.ErrAliasTaken.Error()})
+			return
+		}
+	}
+
+	if json.MaxClicks < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_clicks must not be negative"})
+		return
+	}
+
+	notBefore, notAfter := utcSecond(json.NotBefore), utcSecond(json.NotAfter)
+	if err := checkWindow(notBefore, notAfter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var passwordHash string
+	if json.Password != "" {
+		if passwordHash, err = hashPassword(json.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 去重模式：域名下该用户已有相同长链接时直接返回已有短码，不再分配 ID
+	owner := ownerID(c)
+	custom := json.Alias != "" || expiresAt != nil || passwordHash != "" || json.MaxClicks > 0 || notBefore != nil || notAfter != nil ||
+		targets != nil || geoTargets != nil || variants != nil || query != nil || json.PassQuery != nil
+	urlHash := h.dedupHash(longURL, custom)
+	if urlHash != "" {
+		existing, err := h.findDuplicate(c.Request.Context(), domain, owner, urlHash)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check URL"})
+			return
+		}
+		if existing != nil {
+			c.JSON(http.StatusOK, gin.H{"short_url": h.Domains.ShortURL(domain, h.linkCode(existing))})
+			return
+		}
+	}
+
+	// 预占额度，之后任何原因未能保存新链接都要退还
+	release := h.reserveQuota(c, owner, 1)
+	if release == nil {
 		return
 	}
 
-	req := &pb.Empty{}
-	res, err := IdGenClient.GenerateId(context.Background(), req)
+	id, err := h.generateID(c.Request.Context(), domain)
 	if err != nil {
+		release(1)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to communicate with ID service"})
 		return
 	}
-	id := res.GetId()
 
-	if err := storage.SaveURLWithID(id, json.URL); err != nil {
+	link := &storage.Link{
+		ID:           id,
+		LongURL:      longURL,
+		Targets:      targets,
+		GeoTargets:   geoTargets,
+		Variants:     variants,
+		QueryParams:  query,
+		PassQuery:    json.PassQuery,
+		Domain:       domain,
+		OwnerID:      owner,
+		Alias:        json.Alias,
+		URLHash:      urlHash,
+		PasswordHash: passwordHash,
+		MaxClicks:    json.MaxClicks,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		ExpiresAt:    expiresAt,
+	}
+	shortCode := h.Codec.Encode(id)
+	if json.Alias != "" {
+		shortCode = json.Alias
+	}
+	err = h.Store.SaveLink(c.Request.Context(), link)
+	if err != nil {
+		release(1)
+	}
+	if errors.Is(err, storage.ErrAliasTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, storage.ErrURLExists) {
+		// 并发请求抢先保存了相同长链接，返回其短码
+		existing, err := h.findDuplicate(c.Request.Context(), domain, owner, urlHash)
+		if err == nil && existing != nil {
+			c.JSON(http.StatusOK, gin.H{"short_url": h.Domains.ShortURL(domain, h.linkCode(existing))})
+			return
+		}
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save URL"})
 		return
 	}
 
-	shortCode := toBase62(id)
-	storage.BF.Add(shortCode) // 加入布隆过滤器
+	key := scopedKey(domain, shortCode)
+	h.Bloom.Add(key) // 加入布隆过滤器
+	if json.Alias != "" {
+		// 别名可能曾被删除过，清除遗留的墓碑
+		h.Cache.Del(c.Request.Context(), key)
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"short_url": "http://localhost:8080/" + shortCode,
-	})
+	resp := gin.H{
+		"short_url": h.Domains.ShortURL(domain, shortCode),
+	}
+	if expiresAt != nil {
+		resp["expires_at"] = expiresAt.Format(time.RFC3339)
+	}
+	if targets != nil {
+		resp["targets"] = targets
+	}
+	if geoTargets != nil {
+		resp["geo_targets"] = geoTargets
+	}
+	if variants != nil {
+		resp["variants"] = variants
+	}
+	if query != nil {
+		resp["query_params"] = query
+	}
+	if json.PassQuery != nil {
+		resp["pass_query"] = *json.PassQuery
+	}
+	if json.MaxClicks > 0 {
+		resp["max_clicks"] = json.MaxClicks
+	}
+	if notBefore != nil {
+		resp["not_before"] = notBefore.Format(time.RFC3339)
+	}
+	if notAfter != nil {
+		resp["not_after"] = notAfter.Format(time.RFC3339)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// bindError 描述请求体无法解析的原因 (如 JSON 格式错误、字段类型不符、时间不是 RFC3339 格式)
+func bindError(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type)
+	}
+	return "Invalid request body: " + err.Error()
+}
+
+// resolveExpiry 根据 expires_at 或 ttl_seconds 计算过期时间 (UTC)，二者互斥
+func resolveExpiry(expiresAt *time.Time, ttlSeconds int64, now time.Time) (*time.Time, error) {
+	switch {
+	case expiresAt != nil && ttlSeconds != 0:
+		return nil, errors.New("expires_at and ttl_seconds are mutually exclusive")
+	case ttlSeconds < 0:
+		return nil, errors.New("ttl_seconds must be positive")
+	case ttlSeconds > 0:
+		t := now.Add(time.Duration(ttlSeconds) * time.Second).UTC().Truncate(time.Second)
+		return &t, nil
+	case expiresAt != nil:
+		if !expiresAt.After(now) {
+			return nil, errors.New("expires_at must be in the future")
+		}
+		t := expiresAt.UTC().Truncate(time.Second)
+		return &t, nil
+	}
+	return nil, nil
+}
+
+// generateID 从本地号段取一个新 ID，跳过编码后与域名内已有别名重名的 ID
+func (h *Handler) generateID(ctx context.Context, domain string) (int64, error) {
+	ids, err := h.generateIDs(ctx, domain, 1)
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
 }
 
-func RedirectHandler(c *gin.Context) {
+// generateIDs 从本地号段批量取 count 个 ID，与域名内别名重名的 ID 会被丢弃并补充
+func (h *Handler) generateIDs(ctx context.Context, domain string, count int) ([]int64, error) {
+	ids := make([]int64, 0, count)
+	for len(ids) < count {
+		batch, err := h.IDs.Take(ctx, count-len(ids))
+		if err != nil {
+			return nil, err
+		}
+		codes := make([]string, len(batch))
+		for i, id := range batch {
+			codes[i] = h.Codec.Encode(id)
+		}
+		shadowed, err := h.codesShadowedByAlias(ctx, domain, codes)
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range batch {
+			if !shadowed[codes[i]] {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// lookupLink 从数据库解析域名下的短码：可解码的短码按 ID 查询，否则 (或未命中时) 按别名查询
+// 带别名的链接只能通过别名访问，不能通过其 ID 编码访问；链接只能通过所属域名访问
+func (h *Handler) lookupLink(ctx context.Context, domain, shortCode string) (*storage.Link, error) {
+	if id, ok := h.Codec.Decode(shortCode); ok {
+		link, err := h.Store.GetLink(ctx, id)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		if err == nil && link.Domain == domain && (link.Alias == "" || link.Alias == shortCode) {
+			return link, nil
+		}
+	}
+	return h.Store.GetLinkByAlias(ctx, domain, shortCode)
+}
+
+// Redirect 短链接跳转 GET /:shortURL
+// 受密码保护的链接先展示密码页面，页面提交 (POST /:shortURL) 且密码正确后再跳转
+// 不在生效时间窗口内的链接跳转到 FallbackURL (未配置时返回 404)
+// 设置了 geo_targets 的链接按客户端 IP 所在国家选择目标地址，设置了 targets 的链接按 User-Agent
+// 识别的设备平台选择目标地址 (国家优先)，都未匹配时跳转到长链接
+// 设置了 variants 的 A/B 分流链接按访客标识固定分配到一个分组，以该分组的地址代替长链接
+// 最后按链接与持有者模板合并查询参数 (如 UTM)，并按设置透传访客附加在短链接上的查询参数
+func (h *Handler) Redirect(c *gin.Context) {
 	shortCode := c.Param("shortURL")
+	domain := h.Domains.Resolve(c.Request.Host) // 按 Host 头区分域名
+	key := scopedKey(domain, shortCode)
+	ctx := c.Request.Context()
 
 	// 1. 布隆过滤器拦截
-	exists, err := storage.BF.Exists(shortCode)
+	exists, err := h.Bloom.Exists(key)
 	if err == nil && !exists {
 		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found (intercepted)"})
 		return
 	}
 
-	// 2. 查缓存
-	longURL, err := storage.Rdb.Get(storage.Ctx, shortCode).Result()
-	if err != nil {
-		// 3. 查数据库
-		id := fromBase62(shortCode)
-		longURL, err = storage.GetLongURL(id)
+	// 2. 查缓存 (墓碑表示链接已删除，gone 表示访问次数已用完，inactive 表示不在生效时间窗口内)
+	var target cachedLink
+	cached, err := h.Cache.Get(ctx, key)
+	if err == nil && cached == cacheTombstone {
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		return
+	}
+	if err == nil && cached == cacheGone {
+		c.JSON(http.StatusGone, gin.H{"error": "URL has reached its click limit"})
+		return
+	}
+	if err == nil && cached == cacheInactive {
+		h.inactive(c)
+		return
+	}
+	hit := false
+	if err == nil {
+		target, hit = decodeCachedLink(cached)
+	}
+	if !hit {
+		// 3. 查数据库 (支持自定义别名)
+		link, err := h.lookupLink(ctx, domain, shortCode)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
 			return
 		}
-		storage.Rdb.Set(storage.Ctx, shortCode, longURL, 0)
+		now := time.Now()
+		if link.Expired(now) {
+			c.JSON(http.StatusGone, gin.H{"error": "URL has expired"})
+			return
+		}
+		if link.Exhausted() {
+			h.Cache.Set(ctx, key, cacheGone, tombstoneTTL)
+			c.JSON(http.StatusGone, gin.H{"error": "URL has reached its click limit"})
+			return
+		}
+		// 缓存有效期截止到下一个生效、停止生效或过期时刻，届时缓存自动失效并按新状态回填
+		if !link.Active(now) {
+			h.Cache.Set(ctx, key, cacheInactive, link.CacheTTL(now))
+			h.inactive(c)
+			return
+		}
+		target = newCachedLink(link)
+		h.Cache.Set(ctx, key, target.encode(), link.CacheTTL(now))
 	}
 
-	// 4. (核心) 异步发送分析数据到 Kafka
+	// 4. 密码保护
+	if target.PasswordHash != "" && !h.checkPassword(c, key, target.PasswordHash) {
+		return
+	}
+
+	// 5. 限次链接：原子地计入本次访问，次数用完后将缓存替换为 gone 标记
+	if target.MaxClicks > 0 {
+		ok, err := h.claimClick(ctx, target.ID, target.MaxClicks)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record click"})
+			return
+		}
+		if !ok {
+			h.Cache.Set(ctx, key, cacheGone, tombstoneTTL)
+			c.JSON(http.StatusGone, gin.H{"error": "URL has reached its click limit"})
+			return
+		}
+	}
+
+	// 6. 按访问者国家、设备平台选择目标地址 (国家优先)，A/B 分组决定未匹配时的默认地址
+	platform := classifyPlatform(c.GetHeader("User-Agent"))
+	country := h.country(c.ClientIP())
+	dest := target.URL
+	var variant string
+	if len(target.Variants) > 0 {
+		v := pickVariant(target.Variants, visitorID(c), target.ID)
+		dest, variant = v.URL, v.Name
+		c.Header("Cache-Control", "private, no-store") // 分组因访客而异，不能被共享缓存
+	}
+	if len(target.Targets) > 0 {
+		dest = selectTarget(target.Targets, platform, dest)
+		c.Header("Vary", "User-Agent") // 跳转结果随 UA 变化，避免共享缓存混用
+	}
+	if u, ok := target.GeoTargets[country]; ok && country != "" {
+		dest = u
+	}
+
+	// 7. 合并查询参数
+	dest = h.withQuery(dest, target, c.Request.URL, queryContext{
+		code:     shortCode,
+		domain:   h.Domains.Name(domain),
+		platform: platform,
+		country:  country,
+		variant:  variant,
+	})
+
+	// 8. (核心) 异步发送分析数据到 Kafka
 	// 使用 go func 让它不阻塞主线程，保证跳转速度极快
-	go func(sUrl, lUrl, ip, ua string) {
+	go func(sUrl, domain, lUrl, dest, platform, country, variant, ip, ua string) {
 		event := ClickEvent{
-			ShortURL:  sUrl,
-			LongURL:   lUrl,
-			IP:        ip,
-			UserAgent: ua,
-			Timestamp: time.Now().Unix(),
+			ShortURL:    sUrl,
+			Domain:      domain,
+			LongURL:     lUrl,
+			Destination: dest,
+			Platform:    platform,
+			Country:     country,
+			Variant:     variant,
+			IP:          ip,
+			UserAgent:   ua,
+			Timestamp:   time.Now().Unix(),
 		}
 		jsonBytes, _ := json.Marshal(event)
 
-		// 写入 Kafka
-		storage.KafkaWriter.WriteMessages(context.Background(),
-			kafka.Message{
-				Value: jsonBytes,
-			},
-		)
-	}(shortCode, longURL, c.ClientIP(), c.GetHeader("User-Agent"))
+		// 写入 Kafka (单机模式下输出到日志)
+		h.Events.Publish(context.Background(), jsonBytes)
+	}(shortCode, h.Domains.Name(domain), target.URL, dest, platform, country, variant, c.ClientIP(), c.GetHeader("User-Agent"))
 
-	// 5. 跳转
-	c.Redirect(http.StatusFound, longURL)
+	// 9. 跳转 (表单提交后用 303，让浏览器以 GET 访问目标地址)
+	status := http.StatusFound
+	if c.Request.Method == http.MethodPost {
+		status = http.StatusSeeOther
+	}
+	c.Redirect(status, dest)
 }
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestShortenBindErrors 请求体无法解析时返回具体原因，只有缺少 url 时才提示 "URL is required"
+func TestShortenBindErrors(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		name, body, want string
+	}{
+		{"missing url", `{}`, "URL is required"},
+		{"empty url", `{"url": " "}`, "URL is required"},
+		{"malformed JSON", `{"url": "https://example.com/"`, "Invalid request body"},
+		{"empty body", ``, "Invalid request body"},
+		{"expires_at not RFC3339", `{"url": "https://example.com/", "expires_at": "tomorrow"}`, `parsing time "tomorrow"`},
+		{"not_before not RFC3339", `{"url": "https://example.com/", "not_before": "2026-03-01"}`, "parsing time"},
+		{"not_after wrong type", `{"url": "https://example.com/", "not_after": 1700000000}`, "not_after must be of type time.Time"},
+		{"ttl_seconds wrong type", `{"url": "https://example.com/", "ttl_seconds": "60"}`, "ttl_seconds must be of type int64"},
+		{"max_clicks wrong type", `{"url": "https://example.com/", "max_clicks": 1.5}`, "max_clicks must be of type int64"},
+		{"url wrong type", `{"url": 42}`, "url must be of type string"},
+	}
+	for _, tt := range tests {
+		w := s.do(t, http.MethodPost, "/shorten", tt.body)
+		if got, _ := decodeBody(t, w)["error"].(string); w.Code != http.StatusBadRequest || !strings.Contains(got, tt.want) {
+			t.Errorf("%s: %d %q, want 400 containing %q", tt.name, w.Code, got, tt.want)
+		}
+	}
+}
+
+func TestBatchBindErrors(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		name, body, want string
+	}{
+		{"missing urls", `{}`, "urls is required"},
+		{"empty urls", `{"urls": []}`, "urls must contain between 1 and 1000 items"},
+		{"urls wrong type", `{"urls": "https://example.com/"}`, "urls must be of type []string"},
+		{"expires_at not RFC3339", `{"urls": ["https://example.com/"], "expires_at": "soon"}`, "parsing time"},
+	}
+	for _, tt := range tests {
+		w := s.do(t, http.MethodPost, "/shorten/batch", tt.body)
+		if got, _ := decodeBody(t, w)["error"].(string); w.Code != http.StatusBadRequest || !strings.Contains(got, tt.want) {
+			t.Errorf("%s: %d %q, want 400 containing %q", tt.name, w.Code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/yin1895/tinylink/pkg/proto"
+)
+
+// IDAllocator 为新链接分配全局唯一 ID
+type IDAllocator interface {
+	Take(ctx context.Context, n int) ([]int64, error)
+}
+
+// idBufferSize 每次向 ID 生成器申请的号段大小
+const idBufferSize = 500
+
+// idLowWater 本地剩余 ID 少于此数时在后台预取下一个号段，避免请求等待 RPC
+const idLowWater = idBufferSize / 5
+
+// idFetchTimeout 单次号段申请的超时时间
+// 申请由多个请求共享，不随发起它的请求取消而中断
+const idFetchTimeout = 5 * time.Second
+
+// maxIssuedRanges 为重叠校验保留的已取得区间数量上限，超出时丢弃 ID 最小的区间
+const maxIssuedRanges = 1024
+
+// idRange 连续 ID 区间 [next, end)
+type idRange struct {
+	next, end int64
+}
+
+// idFetch 一次进行中的号段申请，done 关闭后 err 为申请结果
+type idFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// IDBuffer 本地缓存的连续 ID 号段
+// 绝大多数请求直接从本地取号；号段将要耗尽时在后台发起一次 GenerateIdRange RPC，
+// 同一时刻最多只有一个申请在进行，RPC 期间不持有锁，其他请求仍可取用剩余的 ID
+type IDBuffer struct {
+	client pb.IdGeneratorClient
+
+	mu     sync.Mutex
+	ranges []idRange // 已取得尚未用完的号段，按取得顺序排列
+	issued []idRange // 已取得的全部号段 (含已用完的)，按起始位置排序并合并相邻区间，新号段不能与之重叠
+	fetch  *idFetch  // 进行中的申请，没有时为 nil
+}
+
+// NewIDBuffer 创建从 ID 生成器服务补充号段的本地缓冲区
+func NewIDBuffer(client pb.IdGeneratorClient) *IDBuffer {
+	return &IDBuffer{client: client}
+}
+
+// Take 从本地缓冲区取出 n 个 ID，不足时等待向 ID 生成器补充
+func (b *IDBuffer) Take(ctx context.Context, n int) ([]int64, error) {
+	ids := make([]int64, 0, n)
+	b.mu.Lock()
+	for {
+		ids = b.drain(ids, n)
+		if len(ids) == n {
+			if b.fetch == nil && b.remaining() < idLowWater {
+				b.startFetch(ctx, idBufferSize)
+			}
+			b.mu.Unlock()
+			return ids, nil
+		}
+		f := b.fetch
+		if f == nil {
+			f = b.startFetch(ctx, n-len(ids))
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-f.done:
+		}
+		if f.err != nil {
+			return nil, f.err
+		}
+		b.mu.Lock()
+	}
+}
+
+// drain 从本地号段中取号追加到 ids，直到取满 n 个或号段用完，调用方持有锁
+func (b *IDBuffer) drain(ids []int64, n int) []int64 {
+	for len(ids) < n && len(b.ranges) > 0 {
+		r := &b.ranges[0]
+		for len(ids) < n && r.next < r.end {
+			ids = append(ids, r.next)
+			r.next++
+		}
+		if r.next >= r.end {
+			b.ranges = b.ranges[1:]
+		}
+	}
+	return ids
+}
+
+// remaining 返回本地剩余的 ID 数量，调用方持有锁
+func (b *IDBuffer) remaining() int64 {
+	var n int64
+	for _, r := range b.ranges {
+		n += r.end - r.next
+	}
+	return n
+}
+
+// startFetch 在后台申请至少 want 个 ID (不超过单次申请上限)，调用方持有锁且没有进行中的申请
+func (b *IDBuffer) startFetch(ctx context.Context, want int) *idFetch {
+	f := &idFetch{done: make(chan struct{})}
+	b.fetch = f
+	count := int32(min(max(idBufferSize, want), maxBatchSize))
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idFetchTimeout)
+	go func() {
+		defer cancel()
+		res, err := b.client.GenerateIdRange(ctx, &pb.GenerateIdRangeRequest{Count: count})
+
+		b.mu.Lock()
+		if err == nil {
+			err = b.add(res.GetStart(), int64(res.GetCount()))
+		}
+		f.err = err
+		b.fetch = nil
+		b.mu.Unlock()
+		close(f.done)
+	}()
+	return f
+}
+
+// add 校验并保存 ID 生成器返回的号段，调用方持有锁
+// 多个生成器副本的号段可能乱序到达，只要求新号段不与已取得的号段重叠；
+// 空号段或重叠的号段说明生成器异常，直接报错，避免调用方反复重试
+func (b *IDBuffer) add(start, count int64) error {
+	if count <= 0 {
+		return fmt.Errorf("id generator returned an empty range (start %d, count %d)", start, count)
+	}
+	if start < 1 {
+		return fmt.Errorf("id generator returned range starting at %d, IDs must be positive", start)
+	}
+	r := idRange{next: start, end: start + count}
+	i := sort.Search(len(b.issued), func(i int) bool { return b.issued[i].end > r.next })
+	if i < len(b.issued) && b.issued[i].next < r.end {
+		return fmt.Errorf("id generator returned range [%d, %d) overlapping [%d, %d) already received",
+			r.next, r.end, b.issued[i].next, b.issued[i].end)
+	}
+	b.ranges = append(b.ranges, r)
+	b.record(i, r)
+	return nil
+}
+
+// record 将新号段插入 issued 的第 i 个位置并与相邻区间合并，调用方持有锁
+func (b *IDBuffer) record(i int, r idRange) {
+	if i > 0 && b.issued[i-1].end == r.next {
+		i--
+		r.next = b.issued[i].next
+		b.issued = slices.Delete(b.issued, i, i+1)
+	}
+	if i < len(b.issued) && b.issued[i].next == r.end {
+		r.end = b.issued[i].end
+		b.issued = slices.Delete(b.issued, i, i+1)
+	}
+	b.issued = slices.Insert(b.issued, i, r)
+	if len(b.issued) > maxIssuedRanges {
+		b.issued = slices.Delete(b.issued, 0, len(b.issued)-maxIssuedRanges)
+	}
+}
+
+// SequenceIDs 进程内自增 ID 分配器，供单机模式使用 (无需 ID 生成器服务)
+type SequenceIDs struct {
+	last atomic.Int64
+}
+
+// NewSequenceIDs 创建从 start 开始分配的自增 ID 分配器
+func NewSequenceIDs(start int64) *SequenceIDs {
+	s := &SequenceIDs{}
+	s.last.Store(start - 1)
+	return s
+}
+
+// Take 分配 n 个连续 ID
+func (s *SequenceIDs) Take(ctx context.Context, n int) ([]int64, error) {
+	end := s.last.Add(int64(n))
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = end - int64(n) + int64(i) + 1
+	}
+	return ids, nil
+}
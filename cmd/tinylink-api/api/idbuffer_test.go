@@ -0,0 +1,315 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/yin1895/tinylink/pkg/proto"
+
+	"google.golang.org/grpc"
+)
+
+// fakeIDClient 按顺序分配号段的 ID 生成器；reply 非空时改用它决定返回值，
+// gate 非空时每次调用先等待 gate 放行
+type fakeIDClient struct {
+	mu       sync.Mutex
+	next     int64
+	calls    int
+	inFlight int
+	maxInFl  int
+	reply    func(count int32) *pb.GenerateIdRangeResponse
+	gate     chan struct{}
+}
+
+func (c *fakeIDClient) GenerateId(ctx context.Context, in *pb.Empty, opts ...grpc.CallOption) (*pb.GenerateIdResponse, error) {
+	return nil, errors.New("not used")
+}
+
+func (c *fakeIDClient) GenerateIdRange(ctx context.Context, in *pb.GenerateIdRangeRequest, opts ...grpc.CallOption) (*pb.GenerateIdRangeResponse, error) {
+	c.mu.Lock()
+	c.calls++
+	c.inFlight++
+	c.maxInFl = max(c.maxInFl, c.inFlight)
+	gate := c.gate
+	c.mu.Unlock()
+
+	if gate != nil {
+		select {
+		case <-gate:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	if c.reply != nil {
+		return c.reply(in.GetCount()), nil
+	}
+	if c.next == 0 {
+		c.next = 1
+	}
+	res := &pb.GenerateIdRangeResponse{Start: c.next, Count: in.GetCount()}
+	c.next += int64(in.GetCount())
+	return res, nil
+}
+
+func (c *fakeIDClient) callCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+// takeWithin 在 d 内完成 Take，超时视为阻塞
+func takeWithin(t *testing.T, b *IDBuffer, n int, d time.Duration) ([]int64, error) {
+	t.Helper()
+	type result struct {
+		ids []int64
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		ids, err := b.Take(context.Background(), n)
+		ch <- result{ids, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.ids, r.err
+	case <-time.After(d):
+		t.Fatalf("Take(%d) did not return within %v", n, d)
+		return nil, nil
+	}
+}
+
+func TestIDBufferSequential(t *testing.T) {
+	client := &fakeIDClient{}
+	b := NewIDBuffer(client)
+	var want int64 = 1
+	for _, n := range []int{1, 10, 300, 189, 1, 1000, 7} {
+		ids, err := takeWithin(t, b, n, time.Second)
+		if err != nil {
+			t.Fatalf("Take(%d): %v", n, err)
+		}
+		if len(ids) != n {
+			t.Fatalf("Take(%d) returned %d IDs", n, len(ids))
+		}
+		for _, id := range ids {
+			if id != want {
+				t.Fatalf("Take(%d): got ID %d, want %d", n, id, want)
+			}
+			want++
+		}
+	}
+}
+
+func TestIDBufferRejectsBadRanges(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply func(count int32) *pb.GenerateIdRangeResponse
+	}{
+		{"empty range", func(int32) *pb.GenerateIdRangeResponse { return &pb.GenerateIdRangeResponse{Start: 1, Count: 0} }},
+		{"negative count", func(int32) *pb.GenerateIdRangeResponse { return &pb.GenerateIdRangeResponse{Start: 1, Count: -5} }},
+		{"zero start", func(c int32) *pb.GenerateIdRangeResponse { return &pb.GenerateIdRangeResponse{Start: 0, Count: c} }},
+		{"missing reply fields", func(int32) *pb.GenerateIdRangeResponse { return &pb.GenerateIdRangeResponse{} }},
+	}
+	for _, tt := range tests {
+		client := &fakeIDClient{reply: tt.reply}
+		b := NewIDBuffer(client)
+		if _, err := takeWithin(t, b, 1, time.Second); err == nil {
+			t.Errorf("%s: Take succeeded, want error", tt.name)
+		}
+		if calls := client.callCount(); calls != 1 {
+			t.Errorf("%s: %d RPCs, want 1", tt.name, calls)
+		}
+	}
+}
+
+// TestIDBufferRejectsOverlappingRange 生成器返回的号段与已取得的号段重叠时报错
+func TestIDBufferRejectsOverlappingRange(t *testing.T) {
+	client := &fakeIDClient{reply: func(c int32) *pb.GenerateIdRangeResponse {
+		return &pb.GenerateIdRangeResponse{Start: 1, Count: c}
+	}}
+	b := NewIDBuffer(client)
+	if _, err := takeWithin(t, b, idBufferSize, time.Second); err != nil {
+		t.Fatalf("first range: %v", err)
+	}
+	if _, err := takeWithin(t, b, 1, time.Second); err == nil {
+		t.Error("Take with a repeated range succeeded, want error")
+	}
+}
+
+// TestIDBufferOutOfOrderRanges 多个生成器副本的号段乱序到达时，不重叠的号段都被接受
+func TestIDBufferOutOfOrderRanges(t *testing.T) {
+	starts := []int64{1001, 1, 2001, 501}
+	client := &fakeIDClient{}
+	client.reply = func(c int32) *pb.GenerateIdRangeResponse {
+		start := int64(3001) // 最后一次取号触发的预取
+		if client.calls <= len(starts) {
+			start = starts[client.calls-1]
+		}
+		return &pb.GenerateIdRangeResponse{Start: start, Count: idBufferSize}
+	}
+	b := NewIDBuffer(client)
+
+	seen := make(map[int64]bool)
+	for range len(starts) {
+		ids, err := takeWithin(t, b, idBufferSize, time.Second)
+		if err != nil {
+			t.Fatalf("Take after %d RPCs: %v", client.callCount(), err)
+		}
+		for _, id := range ids {
+			if seen[id] {
+				t.Fatalf("ID %d allocated twice", id)
+			}
+			seen[id] = true
+		}
+	}
+	if len(seen) != 4*idBufferSize || !seen[1] || !seen[1500] || seen[1501] || !seen[2500] {
+		t.Errorf("allocated %d IDs, want 1..1500 and 2001..2500", len(seen))
+	}
+}
+
+func TestIDBufferAdd(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges [][2]int64 // 依次添加的 (start, count)，最后一个是被测号段
+		ok     bool
+	}{
+		{"first range", [][2]int64{{1, 10}}, true},
+		{"next range", [][2]int64{{1, 10}, {11, 10}}, true},
+		{"range with a gap", [][2]int64{{1, 10}, {100, 10}}, true},
+		{"earlier range", [][2]int64{{100, 10}, {1, 10}}, true},
+		{"fills a gap exactly", [][2]int64{{1, 10}, {21, 10}, {11, 10}}, true},
+		{"same range", [][2]int64{{1, 10}, {1, 10}}, false},
+		{"overlaps the end", [][2]int64{{1, 10}, {10, 10}}, false},
+		{"overlaps the start", [][2]int64{{100, 10}, {95, 10}}, false},
+		{"inside a range", [][2]int64{{1, 100}, {40, 10}}, false},
+		{"covers a range", [][2]int64{{40, 10}, {1, 100}}, false},
+		{"overlaps merged ranges", [][2]int64{{1, 10}, {11, 10}, {21, 10}, {15, 2}}, false},
+		{"overlaps a drained range", [][2]int64{{1, 10}, {5, 1}}, false},
+		{"zero count", [][2]int64{{1, 0}}, false},
+		{"zero start", [][2]int64{{0, 10}}, false},
+	}
+	for _, tt := range tests {
+		b := NewIDBuffer(nil)
+		var err error
+		for i, r := range tt.ranges {
+			if i == len(tt.ranges)-1 {
+				// 先用完已取得的号段，重叠校验不应依赖尚未用完的号段
+				b.ranges = nil
+			}
+			if err = b.add(r[0], r[1]); err != nil && i < len(tt.ranges)-1 {
+				t.Fatalf("%s: setup range %v: %v", tt.name, r, err)
+			}
+		}
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: add = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+// TestIDBufferPrefetchDoesNotBlock 后台预取进行时，本地剩余的 ID 仍可直接取用
+func TestIDBufferPrefetchDoesNotBlock(t *testing.T) {
+	client := &fakeIDClient{}
+	b := NewIDBuffer(client)
+	// 剩余的 ID 恰好等于预取水位，不触发预取
+	if _, err := takeWithin(t, b, idBufferSize-idLowWater, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// 再取一个后低于预取水位，触发预取，RPC 被挡住
+	client.mu.Lock()
+	client.gate = make(chan struct{})
+	client.mu.Unlock()
+	if _, err := takeWithin(t, b, 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		if _, err := takeWithin(t, b, 10, time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 本地只剩 49 个，需要等待预取完成
+	done := make(chan []int64, 1)
+	go func() {
+		ids, _ := b.Take(context.Background(), 50)
+		done <- ids
+	}()
+	select {
+	case <-done:
+		t.Fatal("Take returned before the prefetch finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(client.gate)
+	select {
+	case ids := <-done:
+		if len(ids) != 50 || ids[0] != idBufferSize-48 || ids[49] != idBufferSize+1 {
+			t.Errorf("Take after prefetch = %d IDs [%d..%d]", len(ids), ids[0], ids[len(ids)-1])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Take did not return after the prefetch finished")
+	}
+	if calls := client.callCount(); calls != 2 {
+		t.Errorf("%d RPCs, want 2", calls)
+	}
+}
+
+// TestIDBufferSingleFetch 并发取号时同一时刻最多只有一个号段申请，且 ID 不重复
+func TestIDBufferSingleFetch(t *testing.T) {
+	client := &fakeIDClient{}
+	b := NewIDBuffer(client)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = make(map[int64]bool)
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				ids, err := b.Take(context.Background(), 7)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				for _, id := range ids {
+					if seen[id] {
+						t.Errorf("ID %d allocated twice", id)
+					}
+					seen[id] = true
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 50*20*7 {
+		t.Errorf("allocated %d IDs, want %d", len(seen), 50*20*7)
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.maxInFl != 1 {
+		t.Errorf("%d concurrent RPCs, want 1", client.maxInFl)
+	}
+}
+
+func TestIDBufferContextCanceled(t *testing.T) {
+	client := &fakeIDClient{gate: make(chan struct{})}
+	defer close(client.gate)
+	b := NewIDBuffer(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := b.Take(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Take with expired context = %v, want DeadlineExceeded", err)
+	}
+}
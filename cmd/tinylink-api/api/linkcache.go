@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/yin1895/tinylink/internal/storage"
+)
+
+// cachedLink 缓存中保存的跳转所需字段，以 JSON 编码
+type cachedLink struct {
+	ID           int64             `json:"i,omitempty"`
+	Owner        string            `json:"o,omitempty"`
+	URL          string            `json:"u"`
+	Targets      map[string]string `json:"t,omitempty"`
+	GeoTargets   map[string]string `json:"g,omitempty"`
+	Variants     []storage.Variant `json:"v,omitempty"`
+	Query        map[string]string `json:"q,omitempty"`
+	PassQuery    *bool             `json:"pq,omitempty"`
+	PasswordHash string            `json:"p,omitempty"`
+	MaxClicks    int64             `json:"m,omitempty"`
+}
+
+func newCachedLink(link *storage.Link) cachedLink {
+	return cachedLink{
+		ID:           link.ID,
+		Owner:        link.OwnerID,
+		URL:          link.LongURL,
+		Targets:      link.Targets,
+		GeoTargets:   link.GeoTargets,
+		Variants:     link.Variants,
+		Query:        link.QueryParams,
+		PassQuery:    link.PassQuery,
+		PasswordHash: link.PasswordHash,
+		MaxClicks:    link.MaxClicks,
+	}
+}
+
+func (l cachedLink) encode() string {
+	b, _ := json.Marshal(l)
+	return string(b)
+}
+
+// decodeCachedLink 解析缓存值，无法解析时返回 false (按未命中处理)
+// 当前版本缓存 JSON 对象 (以 '{' 开头)，其余值视为早期版本直接缓存的长链接
+func decodeCachedLink(v string) (cachedLink, bool) {
+	if !strings.HasPrefix(v, "{") {
+		return cachedLink{URL: v}, v != ""
+	}
+	var l cachedLink
+	if err := json.Unmarshal([]byte(v), &l); err != nil || l.URL == "" {
+		return cachedLink{}, false
+	}
+	return l, true
+}
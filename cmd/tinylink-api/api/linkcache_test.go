@@ -0,0 +1,31 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/yin1895/tinylink/internal/storage"
+)
+
+func TestDecodeCachedLink(t *testing.T) {
+	link := newCachedLink(&storage.Link{ID: 7, LongURL: "https://example.com/", MaxClicks: 3, Targets: map[string]string{"ios": "https://apps.example/"}})
+	got, ok := decodeCachedLink(link.encode())
+	if !ok || got.ID != 7 || got.URL != "https://example.com/" || got.MaxClicks != 3 || got.Targets["ios"] != "https://apps.example/" {
+		t.Errorf("round trip = %+v, %v", got, ok)
+	}
+
+	tests := []struct {
+		name, value, url string
+		ok               bool
+	}{
+		{"legacy long URL", "https://example.com/legacy", "https://example.com/legacy", true},
+		{"empty value", "", "", false},
+		{"malformed JSON", `{"u":`, "", false},
+		{"JSON without URL", `{"i":1}`, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := decodeCachedLink(tt.value)
+		if ok != tt.ok || got.URL != tt.url {
+			t.Errorf("%s: decodeCachedLink(%q) = %+v, %v; want URL %q, %v", tt.name, tt.value, got, ok, tt.url, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yin1895/tinylink/cmd/tinylink-api/middleware"
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cacheTombstone 写入 Redis 的删除标记
+// 布隆过滤器无法删除元素，已删除的短码仍会通过过滤器，
+// 用墓碑拦截后续请求，避免每次都穿透到数据库
+const cacheTombstone = "\x00deleted"
+
+// cacheGone 访问次数已用完的标记，拦截后续请求直接返回 410
+const cacheGone = "\x00gone"
+
+// tombstoneTTL 墓碑 (及已用完标记) 的保留时间
+const tombstoneTTL = 24 * time.Hour
+
+// linkCode 返回链接对外使用的短码
+func (h *Handler) linkCode(link *storage.Link) string {
+	if link.Alias != "" {
+		return link.Alias
+	}
+	return h.Codec.Encode(link.ID)
+}
+
+func (h *Handler) linkResponse(link *storage.Link) gin.H {
+	code := h.linkCode(link)
+	resp := gin.H{
+		"code":       code,
+		"domain":     h.Domains.Name(link.Domain),
+		"short_url":  h.Domains.ShortURL(link.Domain, code),
+		"url":        link.LongURL,
+		"created_at": link.CreatedAt.Format(time.RFC3339),
+		"expired":    link.Expired(time.Now()),
+	}
+	if len(link.Targets) > 0 {
+		resp["targets"] = link.Targets
+	}
+	if len(link.GeoTargets) > 0 {
+		resp["geo_targets"] = link.GeoTargets
+	}
+	if len(link.Variants) > 0 {
+		resp["variants"] = link.Variants
+	}
+	if len(link.QueryParams) > 0 {
+		resp["query_params"] = link.QueryParams
+	}
+	if link.PassQuery != nil {
+		resp["pass_query"] = *link.PassQuery
+	}
+	if link.OwnerID != "" {
+		resp["owner"] = link.OwnerID
+	}
+	if link.Alias != "" {
+		resp["alias"] = link.Alias
+	}
+	if link.PasswordHash != "" {
+		resp["password_protected"] = true
+	}
+	if link.MaxClicks > 0 {
+		resp["max_clicks"] = link.MaxClicks
+		resp["clicks"] = link.Clicks
+	}
+	if link.NotBefore != nil {
+		resp["not_before"] = link.NotBefore.Format(time.RFC3339)
+	}
+	if link.NotAfter != nil {
+		resp["not_after"] = link.NotAfter.Format(time.RFC3339)
+	}
+	if link.NotBefore != nil || link.NotAfter != nil {
+		resp["active"] = link.Active(time.Now())
+	}
+	if link.ExpiresAt != nil {
+		resp["expires_at"] = link.ExpiresAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// ownerID 返回认证通过的用户，未启用认证时为空字符串
+func ownerID(c *gin.Context) string {
+	owner, _ := middleware.OwnerID(c)
+	return owner
+}
+
+// findLink 解析路径中的短码，不存在时直接写 404 并返回 nil
+// 短码所属域名由 domain 查询参数指定，缺省时按 Host 头确定
+// 启用认证时只能找到当前用户创建的链接，他人的链接同样返回 404，不暴露其是否存在
+func (h *Handler) findLink(c *gin.Context) *storage.Link {
+	domain := h.Domains.Resolve(c.Request.Host)
+	if v, ok := c.GetQuery("domain"); ok {
+		var err error
+		if domain, err = h.Domains.Lookup(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil
+		}
+	}
+
+	link, err := h.lookupLink(c.Request.Context(), domain, c.Param("code"))
+	if owner, ok := middleware.OwnerID(c); ok && err == nil && link.OwnerID != owner {
+		err = storage.ErrNotFound
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		return nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load URL"})
+		return nil
+	}
+	return link
+}
+
+// maxListLimit 列表接口单页最大条数
+const maxListLimit = 100
+
+// ListLinks 分页列出链接 GET /api/links?after=<cursor>&limit=<n>
+// 按 ID 升序返回，响应中的 next 作为下一页的 after 参数 (游标经短码编码，不直接暴露 ID)
+// 启用认证时只列出当前用户创建的链接
+func (h *Handler) ListLinks(c *gin.Context) {
+	opts := storage.ListOptions{Limit: 20}
+	if owner, ok := middleware.OwnerID(c); ok {
+		opts.OwnerID = &owner
+	}
+	if v := c.Query("after"); v != "" {
+		after, ok := h.Codec.Decode(v)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		opts.AfterID = after
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxListLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		opts.Limit = limit
+	}
+
+	links, err := h.Store.ListLinks(c.Request.Context(), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list URLs"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(links))
+	for _, link := range links {
+		items = append(items, h.linkResponse(link))
+	}
+	resp := gin.H{"links": items}
+	if len(links) == opts.Limit {
+		resp["next"] = h.Codec.Encode(links[len(links)-1].ID)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetLink 查询链接详情 GET /api/links/:code
+func (h *Handler) GetLink(c *gin.Context) {
+	link := h.findLink(c)
+	if link == nil {
+		return
+	}
+	c.JSON(http.StatusOK, h.linkResponse(link))
+}
+
+// UpdateLink 修改链接 PATCH /api/links/:code
+// 支持修改目标地址 url、设备平台目标 targets、国家目标 geo_targets、A/B 分组 variants 与查询参数 query_params
+// (整体替换，传 null 表示取消)、查询参数透传 pass_query (传 null 表示沿用持有者模板)、访问密码 password (传 null 表示取消密码)、访问次数上限 max_clicks (0 表示不限)、
+// 生效时间窗口 not_before / not_after (传 null 表示不限)，以及 expires_at / ttl_seconds (expires_at 传 null 表示取消过期)
+func (h *Handler) UpdateLink(c *gin.Context) {
+	var req struct {
+		URL        *string         `json:"url"`
+		Targets    json.RawMessage `json:"targets"`
+		GeoTargets json.RawMessage `json:"geo_targets"`
+		Variants   json.RawMessage `json:"variants"`
+		Query      json.RawMessage `json:"query_params"`
+		PassQuery  json.RawMessage `json:"pass_query"`
+		Password   json.RawMessage `json:"password"`
+		MaxClicks  *int64          `json:"max_clicks"`
+		NotBefore  json.RawMessage `json:"not_before"`
+		NotAfter   json.RawMessage `json:"not_after"`
+		ExpiresAt  json.RawMessage `json:"expires_at"`
+		TTLSeconds int64           `json:"ttl_seconds"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindError(err)})
+		return
+	}
+
+	link := h.findLink(c)
+	if link == nil {
+		return
+	}
+
+	if req.URL != nil {
+		longURL, err := h.checkLongURL(*req.URL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		link.LongURL = longURL
+	}
+
+	// targets / geo_targets / variants / query_params: 缺省表示不修改，null 或空值表示取消
+	if len(req.Targets) > 0 {
+		var targets map[string]string
+		if err := json.Unmarshal(req.Targets, &targets); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "targets must be an object of platform to URL or null"})
+			return
+		}
+		checked, err := h.checkTargets(targets)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		link.Targets = checked
+	}
+	if len(req.GeoTargets) > 0 {
+		var targets map[string]string
+		if err := json.Unmarshal(req.GeoTargets, &targets); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "geo_targets must be an object of country code to URL or null"})
+			return
+		}
+		checked, err := h.checkGeoTargets(targets)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		link.GeoTargets = checked
+	}
+	if len(req.Variants) > 0 {
+		var variants []storage.Variant
+		if err := json.Unmarshal(req.Variants, &variants); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "variants must be an array of {name, url, weight} or null"})
+			return
+		}
+		checked, err := h.checkVariants(variants)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		link.Variants = checked
+	}
+	if len(req.Query) > 0 {
+		var params map[string]string
+		if err := json.Unmarshal(req.Query, &params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "query_params must be an object of name to value or null"})
+			return
+		}
+		checked, err := checkQueryParams(params)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		link.QueryParams = checked
+	}
+
+	// pass_query: 缺省表示不修改，null 表示沿用持有者模板
+	if len(req.PassQuery) > 0 {
+		if err := json.Unmarshal(req.PassQuery, &link.PassQuery); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "pass_query must be a boolean or null"})
+			return
+		}
+	}
+
+	// password: 缺省表示不修改，null 表示取消密码
+	if len(req.Password) > 0 {
+		var password *string
+		if err := json.Unmarshal(req.Password, &password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "password must be a string or null"})
+			return
+		}
+		link.PasswordHash = ""
+		if password != nil {
+			hash, err := hashPassword(*password)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			link.PasswordHash = hash
+		}
+	}
+
+	if req.MaxClicks != nil {
+		if *req.MaxClicks < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_clicks must not be negative"})
+			return
+		}
+		link.MaxClicks = *req.MaxClicks
+	}
+
+	// not_before / not_after: 缺省表示不修改，null 表示不限
+	for _, f := range []struct {
+		name  string
+		raw   json.RawMessage
+		field **time.Time
+	}{{"not_before", req.NotBefore, &link.NotBefore}, {"not_after", req.NotAfter, &link.NotAfter}} {
+		t, set, err := patchTime(f.raw, f.name)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if set {
+			*f.field = t
+		}
+	}
+	if err := checkWindow(link.NotBefore, link.NotAfter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// expires_at: 缺省表示不修改，null 表示取消过期
+	clearExpiry := string(req.ExpiresAt) == "null"
+	var expiresAt *time.Time
+	if len(req.ExpiresAt) > 0 && !clearExpiry {
+		expiresAt = new(time.Time)
+		if err := json.Unmarshal(req.ExpiresAt, expiresAt); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be an RFC3339 timestamp"})
+			return
+		}
+	}
+	if clearExpiry && req.TTLSeconds != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at and ttl_seconds are mutually exclusive"})
+		return
+	}
+	newExpiry, err := resolveExpiry(expiresAt, req.TTLSeconds, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if clearExpiry {
+		link.ExpiresAt = nil
+	} else if newExpiry != nil {
+		link.ExpiresAt = newExpiry
+	}
+	// 修改了目标地址或设置了设备平台或国家目标、A/B 分组、查询参数、过期时间、密码、访问次数上限、生效时间窗口的链接不再参与去重
+	if req.URL != nil || link.Targets != nil || link.GeoTargets != nil || link.Variants != nil || link.QueryParams != nil || link.PassQuery != nil || link.ExpiresAt != nil || link.PasswordHash != "" || link.MaxClicks > 0 ||
+		link.NotBefore != nil || link.NotAfter != nil {
+		link.URLHash = ""
+	}
+
+	err = h.Store.UpdateLink(c.Request.Context(), link)
+	if errors.Is(err, storage.ErrNotFound) {
+		// 链接在查询之后被并发删除
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update URL"})
+		return
+	}
+	// 让缓存失效，下次访问时按新数据回填
+	h.Cache.Del(c.Request.Context(), scopedKey(link.Domain, h.linkCode(link)))
+
+	c.JSON(http.StatusOK, h.linkResponse(link))
+}
+
+// DeleteLink 删除链接 DELETE /api/links/:code
+func (h *Handler) DeleteLink(c *gin.Context) {
+	link := h.findLink(c)
+	if link == nil {
+		return
+	}
+
+	err := h.Store.DeleteLink(c.Request.Context(), link.ID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete URL"})
+		return
+	}
+	h.Cache.Set(c.Request.Context(), scopedKey(link.Domain, h.linkCode(link)), cacheTombstone, tombstoneTTL)
+
+	c.Status(http.StatusNoContent)
+}
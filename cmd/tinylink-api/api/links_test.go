@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetLink(t *testing.T) {
+	s := newTestServer(t)
+	expires := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	s.save(t,
+		&storage.Link{ID: 1, LongURL: "https://example.com/a", ExpiresAt: &expires},
+		&storage.Link{ID: 2, LongURL: "https://example.com/b", Alias: "promo", Domain: "go.brand.test"},
+	)
+
+	w := s.do(t, http.MethodGet, "/api/links/1", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/links/1: %d %s", w.Code, w.Body)
+	}
+	body := decodeBody(t, w)
+	want := map[string]any{
+		"code":       "1",
+		"domain":     "short.test",
+		"short_url":  testBaseURL + "/1",
+		"url":        "https://example.com/a",
+		"expires_at": expires.Format(time.RFC3339),
+		"expired":    false,
+	}
+	for k, v := range want {
+		if body[k] != v {
+			t.Errorf("GET /api/links/1: %s = %v, want %v", k, body[k], v)
+		}
+	}
+
+	tests := []struct {
+		name, target string
+		want         int
+	}{
+		{"unknown code", "/api/links/zzz", http.StatusNotFound},
+		{"alias on its domain", "/api/links/promo?domain=go.brand.test", http.StatusOK},
+		{"alias by Host", testBrandURL + "/api/links/promo", http.StatusOK},
+		{"alias on another domain", "/api/links/promo", http.StatusNotFound},
+		{"unknown domain", "/api/links/promo?domain=other.test", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := s.do(t, http.MethodGet, tt.target, nil); w.Code != tt.want {
+			t.Errorf("%s: GET %s = %d, want %d", tt.name, tt.target, w.Code, tt.want)
+		}
+	}
+}
+
+func TestListLinks(t *testing.T) {
+	s := newTestServer(t)
+	for id := int64(1); id <= 5; id++ {
+		s.save(t, &storage.Link{ID: id, LongURL: "https://example.com/" + strconv.FormatInt(id, 10)})
+	}
+
+	var codes []string
+	next := ""
+	for page := 0; page < 5; page++ {
+		target := "/api/links?limit=2"
+		if next != "" {
+			target += "&after=" + next
+		}
+		w := s.do(t, http.MethodGet, target, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", target, w.Code, w.Body)
+		}
+		body := decodeBody(t, w)
+		for _, item := range body["links"].([]any) {
+			codes = append(codes, item.(map[string]any)["code"].(string))
+		}
+		next, _ = body["next"].(string)
+		if next == "" {
+			break
+		}
+	}
+	if got := len(codes); got != 5 || codes[0] != "1" || codes[4] != "5" {
+		t.Errorf("paged through %v, want codes 1..5", codes)
+	}
+
+	for _, target := range []string{"/api/links?limit=0", "/api/links?limit=101", "/api/links?limit=x", "/api/links?after=-"} {
+		if w := s.do(t, http.MethodGet, target, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", target, w.Code)
+		}
+	}
+}
+
+func TestUpdateLink(t *testing.T) {
+	s := newTestServer(t)
+	s.save(t, &storage.Link{ID: 1, LongURL: "https://example.com/old"})
+	// 访问一次，使跳转结果进入缓存
+	if w := s.visit("/1", uaDesktop, "10.0.0.1"); w.Header().Get("Location") != "https://example.com/old" {
+		t.Fatalf("Location before update = %q", w.Header().Get("Location"))
+	}
+
+	w := s.do(t, http.MethodPatch, "/api/links/1", gin.H{"url": "HTTPS://Example.com:443/new", "ttl_seconds": 60})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH: %d %s", w.Code, w.Body)
+	}
+	if body := decodeBody(t, w); body["url"] != "https://example.com/new" || body["expires_at"] == nil {
+		t.Errorf("PATCH response = %v", body)
+	}
+	if w := s.visit("/1", uaDesktop, "10.0.0.1"); w.Header().Get("Location") != "https://example.com/new" {
+		t.Errorf("Location after update = %q, want the new URL (cache invalidated)", w.Header().Get("Location"))
+	}
+
+	// expires_at: null 取消过期，其他字段保持不变
+	w = s.do(t, http.MethodPatch, "/api/links/1", `{"expires_at": null}`)
+	if body := decodeBody(t, w); w.Code != http.StatusOK || body["expires_at"] != nil || body["url"] != "https://example.com/new" {
+		t.Errorf("PATCH expires_at null: %d %v", w.Code, body)
+	}
+
+	tests := []struct {
+		name, target string
+		body         any
+		want         int
+	}{
+		{"invalid URL", "/api/links/1", gin.H{"url": "ftp://example.com/"}, http.StatusBadRequest},
+		{"points to this service", "/api/links/1", gin.H{"url": testBrandURL + "/x"}, http.StatusBadRequest},
+		{"clear expiry and set ttl", "/api/links/1", `{"expires_at": null, "ttl_seconds": 60}`, http.StatusBadRequest},
+		{"expires_at in the past", "/api/links/1", gin.H{"expires_at": time.Now().Add(-time.Hour)}, http.StatusBadRequest},
+		{"unknown code", "/api/links/zzz", gin.H{"url": "https://example.com/"}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := s.do(t, http.MethodPatch, tt.target, tt.body); w.Code != tt.want {
+			t.Errorf("%s: PATCH %s = %d %s, want %d", tt.name, tt.target, w.Code, w.Body, tt.want)
+		}
+	}
+}
+
+func TestDeleteLink(t *testing.T) {
+	s := newTestServer(t)
+	s.save(t, &storage.Link{ID: 1, LongURL: "https://example.com/"})
+	s.visit("/1", uaDesktop, "10.0.0.1") // 写入缓存
+
+	if w := s.do(t, http.MethodDelete, "/api/links/1", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: %d %s", w.Code, w.Body)
+	}
+	// 布隆过滤器仍认为短码存在，由墓碑拦截，不再使用缓存中的跳转结果
+	if w := s.visit("/1", uaDesktop, "10.0.0.1"); w.Code != http.StatusNotFound {
+		t.Errorf("visit after delete = %d, want 404", w.Code)
+	}
+	if w := s.do(t, http.MethodGet, "/api/links/1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET after delete = %d, want 404", w.Code)
+	}
+	if w := s.do(t, http.MethodDelete, "/api/links/1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("second DELETE = %d, want 404", w.Code)
+	}
+}
+
+// deletingStore 在更新之前删除链接，模拟 PATCH 与 DELETE 并发
+type deletingStore struct {
+	storage.LinkStore
+}
+
+func (s deletingStore) UpdateLink(ctx context.Context, link *storage.Link) error {
+	s.LinkStore.DeleteLink(ctx, link.ID)
+	return s.LinkStore.UpdateLink(ctx, link)
+}
+
+func TestUpdateLinkDeletedConcurrently(t *testing.T) {
+	s := newTestServer(t)
+	s.save(t, &storage.Link{ID: 1, LongURL: "https://example.com/"})
+	s.h.Store = deletingStore{s.store}
+	if w := s.do(t, http.MethodPatch, "/api/links/1", gin.H{"url": "https://example.com/new"}); w.Code != http.StatusNotFound {
+		t.Errorf("PATCH of a link deleted meanwhile = %d %s, want 404", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// maxLongURLLen 长链接最大长度，与 urls.long_url 列宽一致
+const maxLongURLLen = 2048
+
+// allowedSchemes 允许跳转的协议，拒绝 javascript:、data: 等
+var allowedSchemes = map[string]struct{}{
+	"http":  {},
+	"https": {},
+}
+
+// defaultPorts 各协议的默认端口，规范化时省略
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// hostProfile 国际化域名转换规则：按查询规则映射大小写等字符，
+// 但允许 '_' 等实际存在于部分主机名中的字符
+var hostProfile = idna.New(idna.MapForLookup(), idna.StrictDomainName(false), idna.VerifyDNSLength(true))
+
+var (
+	errURLRequired    = errors.New("URL is required")
+	errURLTooLong     = errors.New("URL must be at most 2048 characters")
+	errURLInvalid     = errors.New("URL must be an absolute http(s) URL")
+	errURLHost        = errors.New("URL has an invalid host")
+	errURLCredentials = errors.New("URL must not contain credentials")
+	errURLSelf        = errors.New("URL must not point to this service")
+)
+
+// normalizeURL 校验并规范化长链接：
+// 仅允许 http/https 绝对地址，协议与 host 转为小写，国际化域名转为 punycode，
+// 去掉默认端口和 host 末尾的 '.'，空路径补为 '/'
+func normalizeURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errURLRequired
+	}
+	if len(raw) > maxLongURLLen {
+		return nil, errURLTooLong
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Opaque != "" {
+		return nil, errURLInvalid
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if _, ok := allowedSchemes[u.Scheme]; !ok {
+		return nil, errURLInvalid
+	}
+	if u.User != nil {
+		return nil, errURLCredentials
+	}
+
+	host, err := canonicalHost(u.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	port := u.Port()
+	if port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n <= 0 || n > 65535 {
+			return nil, errURLInvalid
+		}
+		port = strconv.Itoa(n) // 去掉前导 0
+	}
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if len(u.String()) > maxLongURLLen {
+		return nil, errURLTooLong
+	}
+	return u, nil
+}
+
+// canonicalHost 将 host 转为小写 ASCII 形式，IP 地址原样返回
+func canonicalHost(host string) (string, error) {
+	host = strings.TrimSuffix(host, ".")
+	if host == "" {
+		return "", errURLInvalid
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+	ascii, err := hostProfile.ToASCII(host)
+	if err != nil {
+		return "", errURLHost
+	}
+	return ascii, nil
+}
+
+// checkLongURL 校验并规范化用户提交的长链接，拒绝指向本服务域名的地址以避免跳转循环
+func (h *Handler) checkLongURL(raw string) (string, error) {
+	u, err := normalizeURL(raw)
+	if err != nil {
+		return "", err
+	}
+	if h.Domains.Owns(u.Host) {
+		return "", errURLSelf
+	}
+	return u.String(), nil
+}
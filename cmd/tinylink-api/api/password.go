@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcrypt 只使用前 72 字节，更长的密码直接拒绝以免产生误解
+const (
+	minPasswordLen = 4
+	maxPasswordLen = 72
+)
+
+var errPasswordLength = errors.New("password must be 4-72 bytes long")
+
+// hashPassword 校验访问密码长度并计算 bcrypt 哈希
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLen || len(password) > maxPasswordLen {
+		return "", errPasswordLength
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// passwordForm 受密码保护链接的输入页面，提交到当前地址
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 22rem; margin: 15vh auto; padding: 0 1rem; }
+input, button { font: inherit; width: 100%; box-sizing: border-box; padding: .5rem; margin-top: .5rem; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+<h1>Password required</h1>
+<p>This link is password protected.</p>
+{{if .}}<p class="error">{{.}}</p>{{end}}
+<form method="post">
+<input type="password" name="password" aria-label="Password" required autofocus>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// renderPasswordForm 输出密码输入页面，errMsg 非空时一并提示
+func renderPasswordForm(c *gin.Context, status int, errMsg string) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := passwordForm.Execute(c.Writer, errMsg); err != nil {
+		log.Printf("Failed to render password form: %v", err)
+	}
+}
+
+// checkPassword 校验受密码保护链接的访问请求，未通过时写出密码页面并返回 false
+// GET 请求展示密码页面；POST 请求校验表单中的密码，
+// 同一客户端对同一链接的尝试次数受 PasswordAttempts 限制，防止暴力破解
+func (h *Handler) checkPassword(c *gin.Context, key, hash string) bool {
+	if c.Request.Method != http.MethodPost {
+		renderPasswordForm(c, http.StatusOK, "")
+		return false
+	}
+
+	if h.PasswordAttempts > 0 {
+		res, err := h.Limits.Allow(c.Request.Context(), "password:"+key+":"+c.ClientIP(), h.PasswordAttempts, h.PasswordWindow)
+		if err != nil {
+			log.Printf("password attempt limit: %v", err)
+		} else if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(max(int(math.Ceil(res.RetryAfter.Seconds())), 1)))
+			renderPasswordForm(c, http.StatusTooManyRequests, "Too many attempts. Please try again later.")
+			return false
+		}
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(c.PostForm("password"))) != nil {
+		renderPasswordForm(c, http.StatusForbidden, "Incorrect password.")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword(t *testing.T) {
+	for _, pw := range []string{"", "abc", strings.Repeat("a", maxPasswordLen+1)} {
+		if _, err := hashPassword(pw); err != errPasswordLength {
+			t.Errorf("hashPassword(%d bytes) = %v, want %v", len(pw), err, errPasswordLength)
+		}
+	}
+	hash, err := hashPassword("open sesame")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte("open sesame")) != nil {
+		t.Error("hash does not match the password")
+	}
+}
+
+// submitPassword 以表单提交访问密码
+func (s *testServer) submitPassword(path, password, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, testBaseURL+path, strings.NewReader(url.Values{"password": {password}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = ip + ":40000"
+	return s.serve(req)
+}
+
+func TestRedirectPassword(t *testing.T) {
+	s := newTestServer(t)
+	s.h.PasswordAttempts, s.h.PasswordWindow = 3, time.Minute
+	code := s.shorten(t, gin.H{"url": "https://example.com/secret", "password": "open sesame"})
+
+	w := s.visit("/"+code, uaDesktop, "10.0.0.1")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `type="password"`) || w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("GET = %d %v, want the password form", w.Code, w.Header())
+	}
+	if w := s.submitPassword("/"+code, "wrong", "10.0.0.1"); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "Incorrect password.") {
+		t.Errorf("wrong password = %d, want 403", w.Code)
+	}
+	w = s.submitPassword("/"+code, "open sesame", "10.0.0.1")
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "https://example.com/secret" {
+		t.Errorf("right password = %d %q, want 303 to the long URL", w.Code, w.Header().Get("Location"))
+	}
+	// 密码页面不产生点击，通过校验后才记录
+	if ev := s.event(t); ev.ShortURL != code {
+		t.Errorf("event = %+v", ev)
+	}
+	select {
+	case ev := <-s.events:
+		t.Errorf("unexpected event %+v", ev)
+	default:
+	}
+
+	// 尝试次数用完后即使密码正确也被拒绝，其他客户端不受影响
+	s.submitPassword("/"+code, "wrong", "10.0.0.1")
+	w = s.submitPassword("/"+code, "open sesame", "10.0.0.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("after %d attempts = %d %v, want 429 with Retry-After", s.h.PasswordAttempts, w.Code, w.Header())
+	}
+	if w := s.submitPassword("/"+code, "open sesame", "10.0.0.2"); w.Code != http.StatusSeeOther {
+		t.Errorf("other client = %d, want 303", w.Code)
+	}
+}
+
+// TestUpdateLinkPassword 通过 PATCH 设置与取消访问密码
+func TestUpdateLinkPassword(t *testing.T) {
+	s := newTestServer(t)
+	code := s.shorten(t, gin.H{"url": "https://example.com/"})
+
+	if w := s.do(t, http.MethodPatch, "/api/links/"+code, gin.H{"password": "abc"}); w.Code != http.StatusBadRequest {
+		t.Errorf("short password = %d, want 400", w.Code)
+	}
+	w := s.do(t, http.MethodPatch, "/api/links/"+code, gin.H{"password": "open sesame"})
+	if body := decodeBody(t, w); w.Code != http.StatusOK || body["password_protected"] != true {
+		t.Fatalf("set password = %d %v", w.Code, body)
+	}
+	if w := s.visit("/"+code, uaDesktop, "10.0.0.1"); w.Code != http.StatusOK {
+		t.Errorf("visit protected link = %d, want the password form", w.Code)
+	}
+
+	w = s.do(t, http.MethodPatch, "/api/links/"+code, gin.H{"password": nil})
+	if body := decodeBody(t, w); w.Code != http.StatusOK || body["password_protected"] != nil {
+		t.Fatalf("remove password = %d %v", w.Code, body)
+	}
+	if w := s.visit("/"+code, uaDesktop, "10.0.0.1"); w.Code != http.StatusFound {
+		t.Errorf("visit after removing the password = %d, want 302", w.Code)
+	}
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// 查询参数的数量与长度限制
+const (
+	maxQueryParams     = 20
+	maxQueryKeyLen     = 64
+	maxQueryValueLen   = 256
+	maxPassQueryLength = 2048 // 透传的访客查询串长度上限，超出部分不透传
+)
+
+// QueryTemplate 持有者的默认查询参数模板
+type QueryTemplate struct {
+	Params    map[string]string // 合并到目标地址的查询参数，链接上的同名参数优先
+	PassQuery bool              // 链接未单独设置 pass_query 时是否透传访客的查询参数
+}
+
+// QueryTemplates 按持有者查找默认查询参数模板
+// 未启用认证时所有链接的持有者均为空字符串，使用 fallback 模板
+type QueryTemplates struct {
+	owners   map[string]QueryTemplate
+	fallback QueryTemplate
+}
+
+// NewQueryTemplates 创建模板集合，owners 中未列出的持有者使用 fallback 模板
+func NewQueryTemplates(owners map[string]QueryTemplate, fallback QueryTemplate) (*QueryTemplates, error) {
+	if _, err := checkQueryParams(fallback.Params); err != nil {
+		return nil, fmt.Errorf("default query template: %w", err)
+	}
+	for _, owner := range slices.Sorted(maps.Keys(owners)) {
+		if _, err := checkQueryParams(owners[owner].Params); err != nil {
+			return nil, fmt.Errorf("query template of %q: %w", owner, err)
+		}
+	}
+	return &QueryTemplates{owners: owners, fallback: fallback}, nil
+}
+
+// For 返回持有者的模板，t 为 nil 时返回空模板
+func (t *QueryTemplates) For(owner string) QueryTemplate {
+	if t == nil {
+		return QueryTemplate{}
+	}
+	if tpl, ok := t.owners[owner]; ok {
+		return tpl
+	}
+	return t.fallback
+}
+
+// checkQueryParams 校验查询参数的数量与长度，为空时返回 nil
+// 配置文件中的模板与接口提交的链接参数使用同样的规则
+func checkQueryParams(params map[string]string) (map[string]string, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	if len(params) > maxQueryParams {
+		return nil, fmt.Errorf("query_params must contain at most %d entries", maxQueryParams)
+	}
+	for _, k := range slices.Sorted(maps.Keys(params)) {
+		if k == "" || len(k) > maxQueryKeyLen {
+			return nil, fmt.Errorf("query_params: name %q must be 1-%d bytes", k, maxQueryKeyLen)
+		}
+		if len(params[k]) > maxQueryValueLen {
+			return nil, fmt.Errorf("query_params.%s: value must be at most %d bytes", k, maxQueryValueLen)
+		}
+	}
+	return params, nil
+}
+
+// queryContext 查询参数值中可用的占位符
+type queryContext struct {
+	code, domain, platform, country, variant string
+}
+
+// expand 替换参数值中的 {code}、{domain}、{platform}、{country}、{variant} 占位符，
+// 无法确定的占位符替换为空字符串
+func (qc queryContext) expand(v string) string {
+	if !strings.Contains(v, "{") {
+		return v
+	}
+	return strings.NewReplacer(
+		"{code}", qc.code,
+		"{domain}", qc.domain,
+		"{platform}", qc.platform,
+		"{country}", strings.ToLower(qc.country),
+		"{variant}", qc.variant,
+	).Replace(v)
+}
+
+// mergeQuery 将查询参数合并到目标地址，优先级从高到低依次为：
+// 目标地址自带的参数、链接的参数、持有者模板的参数、访客透传的参数 (visitor)，
+// 访客只能补充未设置的参数，不能改写链接或模板定义的 UTM 等参数
+// 目标地址原有的查询串保持不变，新参数追加在其后
+func mergeQuery(dest string, visitor url.Values, link, template map[string]string, qc queryContext) string {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return dest
+	}
+	existing, _ := url.ParseQuery(u.RawQuery)
+	extra := url.Values{}
+	for _, params := range []map[string]string{link, template} {
+		for k, v := range params {
+			if _, ok := existing[k]; ok {
+				continue
+			}
+			if _, ok := extra[k]; ok {
+				continue
+			}
+			extra.Set(k, qc.expand(v))
+		}
+	}
+	for k, vs := range visitor {
+		if _, ok := existing[k]; ok {
+			continue
+		}
+		if _, ok := extra[k]; ok {
+			continue
+		}
+		extra[k] = vs
+	}
+	if len(extra) == 0 {
+		return dest
+	}
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += extra.Encode()
+	return u.String()
+}
+
+// withQuery 按链接与持有者模板的设置为目标地址合并查询参数
+func (h *Handler) withQuery(dest string, target cachedLink, requestURL *url.URL, qc queryContext) string {
+	tpl := h.Queries.For(target.Owner)
+	pass := tpl.PassQuery
+	if target.PassQuery != nil {
+		pass = *target.PassQuery
+	}
+	var visitor url.Values
+	if pass {
+		visitor = passQuery(requestURL)
+	}
+	if len(visitor) == 0 && len(target.Query) == 0 && len(tpl.Params) == 0 {
+		return dest
+	}
+	return mergeQuery(dest, visitor, target.Query, tpl.Params, qc)
+}
+
+// passQuery 返回访客附加在短链接上、需要透传的查询参数
+func passQuery(u *url.URL) url.Values {
+	if u.RawQuery == "" || len(u.RawQuery) > maxPassQueryLength {
+		return nil
+	}
+	q, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil
+	}
+	return q
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMergeQuery(t *testing.T) {
+	qc := queryContext{code: "abc", domain: "go.example", platform: "ios", country: "DE", variant: "B"}
+	tests := []struct {
+		name     string
+		dest     string
+		visitor  url.Values
+		link     map[string]string
+		template map[string]string
+		want     string
+	}{
+		{"nothing to add", "https://example.com/p", nil, nil, nil, "https://example.com/p"},
+		{"link params", "https://example.com/p", nil, map[string]string{"utm_source": "news"}, nil,
+			"https://example.com/p?utm_source=news"},
+		{"template params", "https://example.com/p", nil, nil, map[string]string{"utm_medium": "email"},
+			"https://example.com/p?utm_medium=email"},
+		{"link overrides template", "https://example.com/p", nil,
+			map[string]string{"utm_source": "link"}, map[string]string{"utm_source": "template", "utm_medium": "email"},
+			"https://example.com/p?utm_medium=email&utm_source=link"},
+		{"link overrides visitor", "https://example.com/p", url.Values{"utm_source": {"visitor"}, "ref": {"x"}},
+			map[string]string{"utm_source": "link"}, nil,
+			"https://example.com/p?ref=x&utm_source=link"},
+		{"template overrides visitor", "https://example.com/p", url.Values{"utm_source": {"visitor"}, "utm_medium": {"visitor"}},
+			nil, map[string]string{"utm_source": "template"},
+			"https://example.com/p?utm_medium=visitor&utm_source=template"},
+		{"destination overrides everything", "https://example.com/p?utm_source=dest",
+			url.Values{"utm_source": {"visitor"}}, map[string]string{"utm_source": "link"}, map[string]string{"utm_source": "template"},
+			"https://example.com/p?utm_source=dest"},
+		{"existing query kept verbatim", "https://example.com/p?b=2&a=1&raw=%2F", nil, map[string]string{"c": "3"}, nil,
+			"https://example.com/p?b=2&a=1&raw=%2F&c=3"},
+		{"repeated visitor values", "https://example.com/p", url.Values{"tag": {"x", "y"}}, nil, nil,
+			"https://example.com/p?tag=x&tag=y"},
+		{"fragment kept", "https://example.com/p#section", nil, map[string]string{"a": "1"}, nil,
+			"https://example.com/p?a=1#section"},
+		{"values are escaped", "https://example.com/p", nil, map[string]string{"q": "a b&c"}, nil,
+			"https://example.com/p?q=a+b%26c"},
+		{"placeholders", "https://example.com/p", nil,
+			map[string]string{"utm_campaign": "{code}", "src": "{domain}/{platform}/{country}/{variant}"}, nil,
+			"https://example.com/p?src=go.example%2Fios%2Fde%2FB&utm_campaign=abc"},
+		{"placeholders in template", "https://example.com/p", nil, nil, map[string]string{"c": "{code}-{unknown}"},
+			"https://example.com/p?c=abc-%7Bunknown%7D"},
+		{"visitor values are not expanded", "https://example.com/p", url.Values{"v": {"{code}"}}, nil, nil,
+			"https://example.com/p?v=%7Bcode%7D"},
+	}
+	for _, tt := range tests {
+		if got := mergeQuery(tt.dest, tt.visitor, tt.link, tt.template, qc); got != tt.want {
+			t.Errorf("%s: mergeQuery = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQueryContextExpand(t *testing.T) {
+	tests := []struct {
+		qc   queryContext
+		v    string
+		want string
+	}{
+		{queryContext{code: "abc"}, "plain", "plain"},
+		{queryContext{code: "abc"}, "{code}{code}", "abcabc"},
+		{queryContext{country: "US"}, "{country}", "us"},
+		{queryContext{}, "{platform}-{country}-{variant}", "--"},
+		{queryContext{domain: "go.example"}, "{Domain}", "{Domain}"},
+	}
+	for _, tt := range tests {
+		if got := tt.qc.expand(tt.v); got != tt.want {
+			t.Errorf("expand(%q) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestCheckQueryParams(t *testing.T) {
+	many := make(map[string]string)
+	for i := range maxQueryParams + 1 {
+		many[fmt.Sprint("p", i)] = "v"
+	}
+	tests := []struct {
+		name   string
+		params map[string]string
+		err    string
+	}{
+		{"empty", nil, ""},
+		{"ok", map[string]string{"utm_source": "news", "empty": ""}, ""},
+		{"too many", many, "at most 20 entries"},
+		{"empty name", map[string]string{"": "v"}, `name "" must be 1-64 bytes`},
+		{"long name", map[string]string{strings.Repeat("k", 65): "v"}, "must be 1-64 bytes"},
+		{"long value", map[string]string{"k": strings.Repeat("v", 257)}, "query_params.k: value must be at most 256 bytes"},
+	}
+	for _, tt := range tests {
+		_, err := checkQueryParams(tt.params)
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: checkQueryParams: %v", tt.name, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: checkQueryParams error = %v, want containing %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestQueryTemplates(t *testing.T) {
+	fallback := QueryTemplate{Params: map[string]string{"utm_source": "tinylink"}}
+	owners := map[string]QueryTemplate{"alice": {Params: map[string]string{"utm_source": "alice"}, PassQuery: true}}
+	templates, err := NewQueryTemplates(owners, fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := templates.For("alice"); got.Params["utm_source"] != "alice" || !got.PassQuery {
+		t.Errorf("For(alice) = %+v", got)
+	}
+	if got := templates.For("bob"); got.Params["utm_source"] != "tinylink" || got.PassQuery {
+		t.Errorf("For(bob) = %+v", got)
+	}
+	var none *QueryTemplates
+	if got := none.For("alice"); got.Params != nil || got.PassQuery {
+		t.Errorf("nil templates For = %+v", got)
+	}
+
+	bad := map[string]QueryTemplate{"carol": {Params: map[string]string{"": "x"}}}
+	if _, err := NewQueryTemplates(bad, QueryTemplate{}); err == nil || !strings.Contains(err.Error(), `"carol"`) {
+		t.Errorf("NewQueryTemplates with an invalid owner template = %v", err)
+	}
+}
+
+func TestPassQuery(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want url.Values
+	}{
+		{"", nil},
+		{"a=1&b=2", url.Values{"a": {"1"}, "b": {"2"}}},
+		{"a=%zz", nil},
+		{"a=" + strings.Repeat("x", maxPassQueryLength), nil},
+	}
+	for _, tt := range tests {
+		got := passQuery(&url.URL{RawQuery: tt.raw})
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("passQuery(%.20q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestRedirectQueryParams(t *testing.T) {
+	s := newTestServer(t)
+	s.h.Geo = staticCountries{"81.2.69.160": "GB"}
+	code := s.shorten(t, gin.H{
+		"url":          "https://example.com/landing?ref=x",
+		"query_params": gin.H{"utm_source": "tinylink", "utm_campaign": "{code}-{country}"},
+		"pass_query":   true,
+	})
+
+	// 访客参数只能补充，不能改写目标地址或链接定义的参数
+	w := s.visit("/"+code+"?utm_source=visitor&ref=ignored&fbclid=1", uaDesktop, "81.2.69.160")
+	want := "https://example.com/landing?ref=x&fbclid=1&utm_campaign=" + code + "-gb&utm_source=tinylink"
+	if got := w.Header().Get("Location"); got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+	if ev := s.event(t); ev.Destination != want || ev.LongURL != "https://example.com/landing?ref=x" {
+		t.Errorf("event = %+v", ev)
+	}
+}
+
+// TestRedirectQueryTemplate 持有者模板的参数同样不能被访客改写，链接可以关闭模板的透传
+func TestRedirectQueryTemplate(t *testing.T) {
+	s := newTestServer(t)
+	templates, err := NewQueryTemplates(nil, QueryTemplate{Params: map[string]string{"utm_source": "tinylink", "utm_medium": "{platform}"}, PassQuery: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.h.Queries = templates
+	code := s.shorten(t, gin.H{"url": "https://example.com/"})
+	noPass := s.shorten(t, gin.H{"url": "https://example.com/", "query_params": gin.H{"utm_medium": "qr"}, "pass_query": false})
+
+	tests := []struct {
+		path, want string
+	}{
+		{"/" + code + "?utm_source=visitor&utm_term=x", "https://example.com/?utm_medium=desktop&utm_source=tinylink&utm_term=x"},
+		{"/" + noPass + "?utm_term=x", "https://example.com/?utm_medium=qr&utm_source=tinylink"},
+	}
+	for _, tt := range tests {
+		if got := s.visit(tt.path, uaDesktop, "10.0.0.1").Header().Get("Location"); got != tt.want {
+			t.Errorf("visit %s: Location = %q, want %q", tt.path, got, tt.want)
+		}
+		s.event(t)
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errMonthlyQuota = errors.New("monthly link quota exceeded")
+	errActiveQuota  = errors.New("active link quota exceeded")
+)
+
+// Plan 套餐额度，0 表示不限
+type Plan struct {
+	Name         string
+	MonthlyLinks int64 // 每个自然月 (UTC) 可新建的链接数
+	ActiveLinks  int64 // 同时存在的未过期链接数
+}
+
+// Quotas 按持有者统计用量并执行套餐额度
+// 未启用认证时所有链接的持有者均为空字符串，共用同一份额度
+type Quotas struct {
+	usage    storage.UsageStore
+	owners   map[string]Plan // 持有者 -> 套餐
+	fallback Plan            // 未指定套餐的持有者使用的套餐
+}
+
+// NewQuotas 创建配额，owners 中未列出的持有者使用 fallback 套餐
+func NewQuotas(usage storage.UsageStore, owners map[string]Plan, fallback Plan) *Quotas {
+	return &Quotas{usage: usage, owners: owners, fallback: fallback}
+}
+
+// Plan 返回持有者的套餐
+func (q *Quotas) Plan(owner string) Plan {
+	if p, ok := q.owners[owner]; ok {
+		return p
+	}
+	return q.fallback
+}
+
+// Reserve 为持有者预占 n 个新链接的额度，超额时返回 errMonthlyQuota 或 errActiveQuota
+// 返回的 release 用于退还最终未保存的 k 个链接的名额
+// 有效链接数按保存前的统计判断，并发创建时可能略微超出
+func (q *Quotas) Reserve(ctx context.Context, owner string, n int) (release func(k int), err error) {
+	plan := q.Plan(owner)
+	now := time.Now()
+	if plan.ActiveLinks > 0 {
+		active, err := q.usage.CountActiveLinks(ctx, owner, now)
+		if err != nil {
+			return nil, err
+		}
+		if active+int64(n) > plan.ActiveLinks {
+			return nil, errActiveQuota
+		}
+	}
+
+	period := storage.UsagePeriod(now)
+	ok, err := q.usage.ReserveLinks(ctx, owner, period, int64(n), plan.MonthlyLinks)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errMonthlyQuota
+	}
+	return func(k int) {
+		if k <= 0 {
+			return
+		}
+		if err := q.usage.ReleaseLinks(context.Background(), owner, period, int64(k)); err != nil {
+			log.Printf("Failed to release %d quota for %q: %v", k, owner, err)
+		}
+	}, nil
+}
+
+// reserveQuota 预占额度，失败时直接写响应并返回 nil
+func (h *Handler) reserveQuota(c *gin.Context, owner string, n int) func(k int) {
+	release, err := h.Quotas.Reserve(c.Request.Context(), owner, n)
+	if errors.Is(err, errMonthlyQuota) || errors.Is(err, errActiveQuota) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check quota"})
+		return nil
+	}
+	return release
+}
+
+// Usage 查询当前持有者的用量与额度 GET /api/usage
+// limit 为 0 表示不限
+func (h *Handler) Usage(c *gin.Context) {
+	ctx := c.Request.Context()
+	owner := ownerID(c)
+	now := time.Now()
+	period := storage.UsagePeriod(now)
+
+	created, err := h.Quotas.usage.LinksCreated(ctx, owner, period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load usage"})
+		return
+	}
+	active, err := h.Quotas.usage.CountActiveLinks(ctx, owner, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load usage"})
+		return
+	}
+
+	plan := h.Quotas.Plan(owner)
+	c.JSON(http.StatusOK, gin.H{
+		"owner":         owner,
+		"plan":          plan.Name,
+		"period":        period,
+		"monthly_links": gin.H{"used": created, "limit": plan.MonthlyLinks},
+		"active_links":  gin.H{"used": active, "limit": plan.ActiveLinks},
+	})
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestShortenMonthlyQuota(t *testing.T) {
+	s := newTestServer(t)
+	s.h.Quotas = NewQuotas(s.store, nil, Plan{Name: "free", MonthlyLinks: 3})
+
+	s.shorten(t, gin.H{"url": "https://example.com/1"})
+	s.shorten(t, gin.H{"url": "https://example.com/2"})
+	// 校验失败的请求不占用额度
+	if w := s.do(t, http.MethodPost, "/shorten", gin.H{"url": "ftp://example.com/"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid URL = %d", w.Code)
+	}
+	s.shorten(t, gin.H{"url": "https://example.com/3"})
+	w := s.do(t, http.MethodPost, "/shorten", gin.H{"url": "https://example.com/4"})
+	if body := decodeBody(t, w); w.Code != http.StatusForbidden || body["error"] != errMonthlyQuota.Error() {
+		t.Errorf("over quota = %d %v, want 403 %q", w.Code, body, errMonthlyQuota)
+	}
+
+	// 删除链接不退还月度额度
+	if w := s.do(t, http.MethodDelete, "/api/links/1", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d", w.Code)
+	}
+	if w := s.do(t, http.MethodPost, "/shorten", gin.H{"url": "https://example.com/4"}); w.Code != http.StatusForbidden {
+		t.Errorf("after delete = %d, want 403", w.Code)
+	}
+
+	body := decodeBody(t, s.do(t, http.MethodGet, "/api/usage", nil))
+	monthly, _ := body["monthly_links"].(map[string]any)
+	active, _ := body["active_links"].(map[string]any)
+	if body["plan"] != "free" || monthly["used"] != 3.0 || monthly["limit"] != 3.0 || active["used"] != 2.0 || active["limit"] != 0.0 {
+		t.Errorf("GET /api/usage = %v", body)
+	}
+}
+
+func TestShortenActiveQuota(t *testing.T) {
+	s := newTestServer(t)
+	s.h.Quotas = NewQuotas(s.store, nil, Plan{ActiveLinks: 2})
+
+	s.shorten(t, gin.H{"url": "https://example.com/1"})
+	s.shorten(t, gin.H{"url": "https://example.com/2"})
+	w := s.do(t, http.MethodPost, "/shorten", gin.H{"url": "https://example.com/3"})
+	if body := decodeBody(t, w); w.Code != http.StatusForbidden || body["error"] != errActiveQuota.Error() {
+		t.Errorf("over quota = %d %v, want 403 %q", w.Code, body, errActiveQuota)
+	}
+
+	// 删除链接后腾出名额
+	s.do(t, http.MethodDelete, "/api/links/1", nil)
+	s.shorten(t, gin.H{"url": "https://example.com/3"})
+}
+
+// failingSaveStore 保存链接总是失败
+type failingSaveStore struct {
+	storage.LinkStore
+}
+
+func (failingSaveStore) SaveLink(ctx context.Context, link *storage.Link) error {
+	return errors.New("db down")
+}
+
+func (failingSaveStore) SaveLinks(ctx context.Context, links []*storage.Link) error {
+	return errors.New("db down")
+}
+
+// TestQuotaReleasedOnFailure 预占额度后未能保存的链接退还名额
+func TestQuotaReleasedOnFailure(t *testing.T) {
+	s := newTestServer(t)
+	s.h.Quotas = NewQuotas(s.store, nil, Plan{MonthlyLinks: 2})
+
+	s.h.Store = failingSaveStore{s.store}
+	if w := s.do(t, http.MethodPost, "/shorten", gin.H{"url": "https://example.com/1"}); w.Code != http.StatusInternalServerError {
+		t.Fatalf("shorten with a failing store = %d", w.Code)
+	}
+	w := s.do(t, http.MethodPost, "/shorten/batch", gin.H{"urls": []string{"https://example.com/1", "https://example.com/2"}})
+	if body := decodeBody(t, w); w.Code != http.StatusOK || body["failed"] != 2.0 {
+		t.Fatalf("batch with a failing store = %d %v", w.Code, body)
+	}
+
+	s.h.Store = s.store
+	s.shorten(t, gin.H{"url": "https://example.com/1"})
+	s.shorten(t, gin.H{"url": "https://example.com/2"})
+}
+
+// TestQuotaPlans 持有者按指定的套餐计算额度，未指定的使用默认套餐
+func TestQuotaPlans(t *testing.T) {
+	s := newTestServer(t, apiKeyAuth(t, "alice", "bob"))
+	s.h.Quotas = NewQuotas(s.store, map[string]Plan{"alice": {Name: "pro", MonthlyLinks: 2}}, Plan{Name: "free", MonthlyLinks: 1})
+
+	results := map[string][]int{"alice": {200, 200, 403}, "bob": {200, 403, 403}}
+	for owner, want := range results {
+		for i, code := range want {
+			if w := s.doAs(t, owner, http.MethodPost, "/shorten", gin.H{"url": "https://example.com/"}); w.Code != code {
+				t.Errorf("%s request %d = %d, want %d", owner, i+1, w.Code, code)
+			}
+		}
+	}
+	for owner, plan := range map[string]string{"alice": "pro", "bob": "free"} {
+		if body := decodeBody(t, s.doAs(t, owner, http.MethodGet, "/api/usage", nil)); body["plan"] != plan || body["owner"] != owner {
+			t.Errorf("%s: GET /api/usage = %v, want plan %s", owner, body, plan)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yin1895/tinylink/cmd/tinylink-api/middleware"
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 测试服务的默认域名与品牌域名
+const (
+	testBaseURL  = "http://short.test"
+	testBrandURL = "https://go.brand.test"
+)
+
+// 测试中常用的 User-Agent
+const (
+	uaIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) Mobile/15E148 Safari/604.1"
+	uaAndroid = "Mozilla/5.0 (Linux; Android 14; Pixel 8) Chrome/124.0 Mobile Safari/537.36"
+	uaDesktop = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/124.0 Safari/537.36"
+)
+
+// newTestHandler 创建只配置了默认域名的 Handler，供校验函数使用
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	domains, err := NewDomains(testBaseURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Handler{Domains: domains, Codec: NewCodec("", 7, 0)}
+}
+
+// eventRecorder 收集 Redirect 异步发送的点击事件，缓冲区满时丢弃
+type eventRecorder chan ClickEvent
+
+func (r eventRecorder) Publish(ctx context.Context, payload []byte) error {
+	var e ClickEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return err
+	}
+	select {
+	case r <- e:
+	default:
+	}
+	return nil
+}
+
+// testServer 单机模式下的完整 API 服务 (内存存储、缓存与布隆过滤器，顺序发号)，路由与 main 一致
+type testServer struct {
+	h      *Handler
+	store  *storage.MemoryStore
+	router *gin.Engine
+	events eventRecorder
+}
+
+// newTestServer 创建测试服务，mw 用于创建与管理接口 (如认证中间件)
+// 默认域名为 short.test，另有品牌域名 go.brand.test；额度不限
+func newTestServer(t *testing.T, mw ...gin.HandlerFunc) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	domains, err := NewDomains(testBaseURL, []string{testBrandURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := storage.NewMemoryStore()
+	s := &testServer{store: store, events: make(eventRecorder, 64)}
+	s.h = &Handler{
+		Store:   store,
+		Cache:   storage.NewMemoryCache(),
+		Bloom:   storage.NewMemoryBloomFilter(10000, 0.01),
+		Events:  s.events,
+		IDs:     NewSequenceIDs(1),
+		Codec:   NewCodec("", 7, 0),
+		Domains: domains,
+		Quotas:  NewQuotas(store, nil, Plan{}),
+		Limits:  storage.NewMemoryRateLimiter(),
+	}
+
+	// 路由注册时绑定的是方法值，测试中替换 s.h 的字段同样生效
+	s.router = gin.New()
+	s.router.GET("/:shortURL", s.h.Redirect)
+	s.router.POST("/:shortURL", s.h.Redirect)
+	authed := s.router.Group("", mw...)
+	authed.POST("/shorten", s.h.ShortenURL)
+	authed.POST("/shorten/batch", s.h.BatchShorten)
+	authed.GET("/api/links", s.h.ListLinks)
+	authed.GET("/api/links/:code", s.h.GetLink)
+	authed.PATCH("/api/links/:code", s.h.UpdateLink)
+	authed.DELETE("/api/links/:code", s.h.DeleteLink)
+	authed.GET("/api/usage", s.h.Usage)
+	return s
+}
+
+// apiKeyAuth 为每个持有者创建明文为 "<owner>-key" 的 API Key，返回认证中间件
+func apiKeyAuth(t *testing.T, owners ...string) gin.HandlerFunc {
+	t.Helper()
+	keys := storage.NewMemoryKeyStore()
+	for _, owner := range owners {
+		if err := keys.CreateAPIKey(context.Background(), &storage.APIKey{OwnerID: owner, Hash: storage.HashAPIKey(owner + "-key")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return middleware.APIKeyAuth(keys)
+}
+
+// save 直接保存链接并加入布隆过滤器，绕过创建接口的校验
+func (s *testServer) save(t *testing.T, links ...*storage.Link) {
+	t.Helper()
+	for _, link := range links {
+		if err := s.store.SaveLink(context.Background(), link); err != nil {
+			t.Fatal(err)
+		}
+		s.h.Bloom.Add(scopedKey(link.Domain, s.h.linkCode(link)))
+	}
+}
+
+// serve 处理请求并返回响应
+func (s *testServer) serve(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// newJSONRequest 创建请求，body 为 string 时原样发送，其他非 nil 值编码为 JSON
+// target 不含 host 时发往默认域名
+func newJSONRequest(t *testing.T, method, target string, body any) *http.Request {
+	t.Helper()
+	if strings.HasPrefix(target, "/") {
+		target = testBaseURL + target
+	}
+	var raw []byte
+	switch b := body.(type) {
+	case nil:
+	case string:
+		raw = []byte(b)
+	default:
+		var err error
+		if raw, err = json.Marshal(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, target, bytes.NewReader(raw))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req
+}
+
+// do 发送 JSON 请求
+func (s *testServer) do(t *testing.T, method, target string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	return s.serve(newJSONRequest(t, method, target, body))
+}
+
+// doAs 以 owner 的 API Key (见 apiKeyAuth) 发送 JSON 请求
+func (s *testServer) doAs(t *testing.T, owner, method, target string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	req := newJSONRequest(t, method, target, body)
+	req.Header.Set("Authorization", "Bearer "+owner+"-key")
+	return s.serve(req)
+}
+
+// visit 以指定的 User-Agent 与客户端 IP 访问短链接，path 不含 host 时访问默认域名
+func (s *testServer) visit(path, ua, ip string) *httptest.ResponseRecorder {
+	if strings.HasPrefix(path, "/") {
+		path = testBaseURL + path
+	}
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("User-Agent", ua)
+	req.RemoteAddr = ip + ":40000"
+	return s.serve(req)
+}
+
+// shorten 调用 POST /shorten 并返回短码，失败时终止测试
+func (s *testServer) shorten(t *testing.T, body gin.H) string {
+	t.Helper()
+	w := s.do(t, http.MethodPost, "/shorten", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /shorten %v: %d %s", body, w.Code, w.Body)
+	}
+	shortURL, _ := decodeBody(t, w)["short_url"].(string)
+	return shortURL[strings.LastIndex(shortURL, "/")+1:]
+}
+
+// event 等待一次点击事件
+func (s *testServer) event(t *testing.T) ClickEvent {
+	t.Helper()
+	select {
+	case ev := <-s.events:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no click event published")
+		return ClickEvent{}
+	}
+}
+
+// decodeBody 解析 JSON 响应
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", w.Body, err)
+	}
+	return body
+}
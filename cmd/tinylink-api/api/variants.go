@@ -0,0 +1,118 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A/B 分组的数量、名称与权重限制
+const (
+	minVariants       = 2
+	maxVariants       = 10
+	maxVariantNameLen = 32
+	maxVariantWeight  = 10000
+)
+
+// visitorCookie 保存访客标识的 Cookie，同一访客在各个 A/B 链接中的分组保持不变
+const visitorCookie = "tl_vid"
+
+// visitorCookieMaxAge 访客标识的有效期 (秒)
+const visitorCookieMaxAge = 365 * 24 * 60 * 60
+
+var errVariantCount = fmt.Errorf("variants must contain %d-%d entries", minVariants, maxVariants)
+
+// checkVariants 校验 A/B 分组并规范化目标地址，为空时返回 nil
+// 未填名称的分组依次命名为 A、B、C…，未填权重的分组权重为 1
+func (h *Handler) checkVariants(variants []storage.Variant) ([]storage.Variant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	if len(variants) < minVariants || len(variants) > maxVariants {
+		return nil, errVariantCount
+	}
+	checked := make([]storage.Variant, len(variants))
+	seen := make(map[string]bool, len(variants))
+	for i, v := range variants {
+		if v.Name == "" {
+			v.Name = string(rune('A' + i))
+		}
+		if len(v.Name) > maxVariantNameLen {
+			return nil, fmt.Errorf("variants[%d]: name must be at most %d bytes", i, maxVariantNameLen)
+		}
+		if seen[v.Name] {
+			return nil, fmt.Errorf("variants[%d]: duplicate name %q", i, v.Name)
+		}
+		seen[v.Name] = true
+		switch {
+		case v.Weight < 0 || v.Weight > maxVariantWeight:
+			return nil, fmt.Errorf("variants[%d]: weight must be between 1 and %d", i, maxVariantWeight)
+		case v.Weight == 0:
+			v.Weight = 1
+		}
+		u, err := h.checkLongURL(v.URL)
+		if err != nil {
+			return nil, fmt.Errorf("variants[%d]: %w", i, err)
+		}
+		v.URL = u
+		checked[i] = v
+	}
+	return checked, nil
+}
+
+// pickVariant 按权重为访客确定分组：对 (访客, 链接) 取哈希后落入权重区间，
+// 同一访客访问同一链接总是得到相同的分组；修改分组或权重后部分访客会被重新分配
+// 权重按 checkVariants 的范围截断，校验之前保存的超范围权重不会使总和溢出
+func pickVariant(variants []storage.Variant, visitor string, linkID int64) storage.Variant {
+	var total uint64
+	for _, v := range variants {
+		total += variantWeight(v)
+	}
+	if total == 0 {
+		return variants[0]
+	}
+	sum := sha256.Sum256([]byte(visitor + ":" + strconv.FormatInt(linkID, 10)))
+	n := binary.BigEndian.Uint64(sum[:8]) % total
+	for _, v := range variants {
+		w := variantWeight(v)
+		if n < w {
+			return v
+		}
+		n -= w
+	}
+	return variants[len(variants)-1]
+}
+
+// variantWeight 返回截断到 [0, maxVariantWeight] 的分组权重
+func variantWeight(v storage.Variant) uint64 {
+	return uint64(min(max(v.Weight, 0), maxVariantWeight))
+}
+
+// visitorID 返回访客标识：优先使用 Cookie，没有时由 IP 与 User-Agent 的哈希生成并写入 Cookie，
+// 这样即使 Cookie 被禁用，同一网络下的同一浏览器也会被分到相同的组
+func visitorID(c *gin.Context) string {
+	if id, err := c.Cookie(visitorCookie); err == nil && isVisitorID(id) {
+		return id
+	}
+	sum := sha256.Sum256([]byte(c.ClientIP() + "|" + c.GetHeader("User-Agent")))
+	id := hex.EncodeToString(sum[:16])
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(visitorCookie, id, visitorCookieMaxAge, "/", "", c.Request.TLS != nil, true)
+	return id
+}
+
+// isVisitorID 判断 Cookie 中的访客标识格式是否正确 (32 位十六进制)，防止写入任意内容
+func isVisitorID(s string) bool {
+	if len(s) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPickVariantSticky(t *testing.T) {
+	variants := []storage.Variant{
+		{Name: "A", URL: "https://a.example/", Weight: 1},
+		{Name: "B", URL: "https://b.example/", Weight: 1},
+		{Name: "C", URL: "https://c.example/", Weight: 1},
+	}
+	for i := range 100 {
+		visitor := fmt.Sprintf("%032x", i)
+		first := pickVariant(variants, visitor, 42)
+		for range 3 {
+			if again := pickVariant(variants, visitor, 42); again != first {
+				t.Fatalf("visitor %s: got %s then %s", visitor, first.Name, again.Name)
+			}
+		}
+	}
+}
+
+func TestPickVariantWeights(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []storage.Variant
+	}{
+		{"even", []storage.Variant{{Name: "A", Weight: 1}, {Name: "B", Weight: 1}}},
+		{"one to three", []storage.Variant{{Name: "A", Weight: 1}, {Name: "B", Weight: 3}}},
+		{"three way", []storage.Variant{{Name: "A", Weight: 50}, {Name: "B", Weight: 30}, {Name: "C", Weight: 20}}},
+		{"one in a hundred", []storage.Variant{{Name: "A", Weight: 99}, {Name: "B", Weight: 1}}},
+	}
+	const visitors = 20000
+	for _, tt := range tests {
+		var total int
+		for _, v := range tt.variants {
+			total += v.Weight
+		}
+		counts := make(map[string]int)
+		for i := range visitors {
+			counts[pickVariant(tt.variants, fmt.Sprintf("%032x", i), 7).Name]++
+		}
+		for _, v := range tt.variants {
+			want := float64(v.Weight) / float64(total)
+			got := float64(counts[v.Name]) / visitors
+			if math.Abs(got-want) > 0.02 {
+				t.Errorf("%s: variant %s got %.3f of visitors, want %.3f", tt.name, v.Name, got, want)
+			}
+		}
+	}
+}
+
+// TestPickVariantOutOfRange 校验之前保存的超范围权重不会导致总和溢出
+func TestPickVariantOutOfRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []storage.Variant
+		want     string
+	}{
+		{"overflowing weights", []storage.Variant{{Name: "A", Weight: 1 << 62}, {Name: "B", Weight: 1 << 62}, {Name: "C", Weight: 1 << 62}, {Name: "D", Weight: 1 << 62}}, ""},
+		{"negative weight", []storage.Variant{{Name: "A", Weight: -5}, {Name: "B", Weight: 1}}, "B"},
+		{"all zero", []storage.Variant{{Name: "A"}, {Name: "B"}}, "A"},
+	}
+	for _, tt := range tests {
+		for i := range 100 {
+			got := pickVariant(tt.variants, fmt.Sprintf("%032x", i), 7)
+			if tt.want != "" && got.Name != tt.want {
+				t.Errorf("%s: pickVariant = %s, want %s", tt.name, got.Name, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// TestPickVariantPerLink 同一访客在不同链接中的分组相互独立
+func TestPickVariantPerLink(t *testing.T) {
+	variants := []storage.Variant{{Name: "A", Weight: 1}, {Name: "B", Weight: 1}}
+	visitor := strings.Repeat("ab", 16)
+	seen := make(map[string]bool)
+	for id := int64(1); id <= 64; id++ {
+		seen[pickVariant(variants, visitor, id).Name] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("visitor was put in %v across 64 links, want both groups", seen)
+	}
+}
+
+func TestCheckVariants(t *testing.T) {
+	h := newTestHandler(t)
+	tests := []struct {
+		name     string
+		variants []storage.Variant
+		want     []storage.Variant
+		err      string
+	}{
+		{"empty", nil, nil, ""},
+		{"defaults", []storage.Variant{{URL: "https://A.example"}, {URL: "https://b.example/x", Weight: 3}},
+			[]storage.Variant{{Name: "A", URL: "https://a.example/", Weight: 1}, {Name: "B", URL: "https://b.example/x", Weight: 3}}, ""},
+		{"named", []storage.Variant{{Name: "control", URL: "https://a.example/"}, {Name: "new", URL: "https://b.example/"}},
+			[]storage.Variant{{Name: "control", URL: "https://a.example/", Weight: 1}, {Name: "new", URL: "https://b.example/", Weight: 1}}, ""},
+		{"too few", []storage.Variant{{URL: "https://a.example/"}}, nil, "variants must contain 2-10 entries"},
+		{"too many", make([]storage.Variant, 11), nil, "variants must contain 2-10 entries"},
+		{"duplicate name", []storage.Variant{{Name: "x", URL: "https://a.example/"}, {Name: "x", URL: "https://b.example/"}}, nil, `duplicate name "x"`},
+		{"default name collides", []storage.Variant{{Name: "B", URL: "https://a.example/"}, {URL: "https://b.example/"}}, nil, `variants[1]: duplicate name "B"`},
+		{"name too long", []storage.Variant{{Name: strings.Repeat("n", 33), URL: "https://a.example/"}, {URL: "https://b.example/"}}, nil, "variants[0]: name must be at most 32 bytes"},
+		{"negative weight", []storage.Variant{{URL: "https://a.example/", Weight: -1}, {URL: "https://b.example/"}}, nil, "variants[0]: weight must be between 1 and 10000"},
+		{"weight too large", []storage.Variant{{URL: "https://a.example/"}, {URL: "https://b.example/", Weight: 10001}}, nil, "variants[1]: weight must be between 1 and 10000"},
+		{"maximum weight", []storage.Variant{{URL: "https://a.example/", Weight: 10000}, {URL: "https://b.example/"}},
+			[]storage.Variant{{Name: "A", URL: "https://a.example/", Weight: 10000}, {Name: "B", URL: "https://b.example/", Weight: 1}}, ""},
+		{"invalid URL", []storage.Variant{{URL: "https://a.example/"}, {URL: "mailto:x@example.com"}}, nil, "variants[1]:"},
+	}
+	for _, tt := range tests {
+		got, err := h.checkVariants(tt.variants)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: checkVariants error = %v, want containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: checkVariants: %v", tt.name, err)
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: checkVariants = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsVisitorID(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{strings.Repeat("0f", 16), true},
+		{strings.Repeat("0F", 16), true},
+		{strings.Repeat("0f", 15), false},
+		{strings.Repeat("0f", 17), false},
+		{strings.Repeat("zz", 16), false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isVisitorID(tt.s); got != tt.want {
+			t.Errorf("isVisitorID(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestShortenVariants(t *testing.T) {
+	s := newTestServer(t)
+	huge := 1 << 62
+	for _, weights := range [][]int{{huge, huge, huge, huge}, {maxVariantWeight + 1, 1}, {-1, 1}} {
+		variants := make([]gin.H, len(weights))
+		for i, w := range weights {
+			variants[i] = gin.H{"url": fmt.Sprintf("https://example.com/%d", i), "weight": w}
+		}
+		w := s.do(t, http.MethodPost, "/shorten", gin.H{"url": "https://example.com/", "variants": variants})
+		if body := decodeBody(t, w); w.Code != http.StatusBadRequest || !strings.Contains(fmt.Sprint(body["error"]), "weight must be between 1 and 10000") {
+			t.Errorf("weights %v = %d %v, want 400", weights, w.Code, body)
+		}
+	}
+
+	code := s.shorten(t, gin.H{"url": "https://example.com/", "variants": []gin.H{
+		{"name": "control", "url": "https://example.com/a", "weight": maxVariantWeight},
+		{"name": "new", "url": "https://example.com/b"},
+	}})
+	if w := s.do(t, http.MethodPatch, "/api/links/"+code, gin.H{"variants": []gin.H{
+		{"url": "https://example.com/a", "weight": huge},
+		{"url": "https://example.com/b", "weight": huge},
+	}}); w.Code != http.StatusBadRequest {
+		t.Errorf("PATCH with huge weights = %d, want 400", w.Code)
+	}
+
+	// 分组决定默认地址，同一访客 (相同 IP 与 UA) 每次得到相同分组
+	first := s.visit("/"+code, uaDesktop, "10.0.0.2")
+	ev := s.event(t)
+	if first.Code != http.StatusFound || ev.Variant == "" || ev.Destination != first.Header().Get("Location") || first.Header().Get("Cache-Control") != "private, no-store" {
+		t.Fatalf("variant redirect: %d %v, event %+v", first.Code, first.Header(), ev)
+	}
+	for range 3 {
+		again := s.visit("/"+code, uaDesktop, "10.0.0.2")
+		s.event(t)
+		if again.Header().Get("Location") != first.Header().Get("Location") {
+			t.Errorf("visitor moved from %q to %q", first.Header().Get("Location"), again.Header().Get("Location"))
+		}
+	}
+}
+
+// TestRedirectVariantPrecedence 设备平台目标优先于 A/B 分组
+func TestRedirectVariantPrecedence(t *testing.T) {
+	s := newTestServer(t)
+	code := s.shorten(t, gin.H{
+		"url":      "https://example.com/",
+		"variants": []gin.H{{"url": "https://a.example/"}, {"url": "https://b.example/"}},
+		"targets":  gin.H{"android": "https://play.google.com/app"},
+	})
+	w := s.visit("/"+code, uaAndroid, "10.0.0.1")
+	if got := w.Header().Get("Location"); got != "https://play.google.com/app" {
+		t.Errorf("Location = %q, want the android target", got)
+	}
+	if ev := s.event(t); ev.Variant == "" || ev.Platform != platformAndroid {
+		t.Errorf("event = %+v, want the assigned variant and platform android", ev)
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cacheInactive 链接不在生效时间窗口内的标记，有效期截止到下一个生效或停止生效时刻
+const cacheInactive = "\x00inactive"
+
+var errWindowOrder = errors.New("not_after must be after not_before")
+
+// checkWindow 校验生效时间窗口，两端都设置时 not_after 必须晚于 not_before
+func checkWindow(notBefore, notAfter *time.Time) error {
+	if notBefore != nil && notAfter != nil && !notAfter.After(*notBefore) {
+		return errWindowOrder
+	}
+	return nil
+}
+
+// utcSecond 统一为 UTC 并截断到秒，与数据库的精度一致
+func utcSecond(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC().Truncate(time.Second)
+	return &u
+}
+
+// patchTime 解析 PATCH 请求中的可选时间字段：缺省表示不修改 (set 为 false)，null 表示清除
+func patchTime(raw json.RawMessage, name string) (t *time.Time, set bool, err error) {
+	if len(raw) == 0 {
+		return nil, false, nil
+	}
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return nil, false, fmt.Errorf("%s must be an RFC3339 timestamp or null", name)
+	}
+	return utcSecond(t), true, nil
+}
+
+// inactive 响应生效时间窗口外的访问：配置了兜底地址时跳转过去，否则返回 404
+func (h *Handler) inactive(c *gin.Context) {
+	if h.FallbackURL == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL is not active"})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, h.FallbackURL)
+}
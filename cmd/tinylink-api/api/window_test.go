@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yin1895/tinylink/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckWindow(t *testing.T) {
+	t0 := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+	tests := []struct {
+		name                string
+		notBefore, notAfter *time.Time
+		want                error
+	}{
+		{"none", nil, nil, nil},
+		{"start only", &t0, nil, nil},
+		{"end only", nil, &t1, nil},
+		{"ordered", &t0, &t1, nil},
+		{"equal", &t0, &t0, errWindowOrder},
+		{"reversed", &t1, &t0, errWindowOrder},
+	}
+	for _, tt := range tests {
+		if err := checkWindow(tt.notBefore, tt.notAfter); !errors.Is(err, tt.want) {
+			t.Errorf("%s: checkWindow = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestUTCSecond(t *testing.T) {
+	if utcSecond(nil) != nil {
+		t.Error("utcSecond(nil) != nil")
+	}
+	in := time.Date(2026, 5, 1, 10, 30, 15, 999_000_000, time.FixedZone("CST", 8*3600))
+	got := utcSecond(&in)
+	if want := time.Date(2026, 5, 1, 2, 30, 15, 0, time.UTC); !got.Equal(want) || got.Location() != time.UTC {
+		t.Errorf("utcSecond = %v, want %v", got, want)
+	}
+}
+
+func TestPatchTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    *time.Time
+		set     bool
+		wantErr bool
+	}{
+		{"absent", "", nil, false, false},
+		{"null clears", "null", nil, true, false},
+		{"timestamp", `"2026-05-01T10:00:00.5+08:00"`, func() *time.Time { t := time.Date(2026, 5, 1, 2, 0, 0, 0, time.UTC); return &t }(), true, false},
+		{"not a timestamp", `"tomorrow"`, nil, false, true},
+		{"wrong type", `123`, nil, false, true},
+	}
+	for _, tt := range tests {
+		got, set, err := patchTime(json.RawMessage(tt.raw), "not_before")
+		if (err != nil) != tt.wantErr || set != tt.set {
+			t.Errorf("%s: patchTime = %v, %v, %v", tt.name, got, set, err)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+			t.Errorf("%s: patchTime = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRedirectWindow(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now().UTC()
+	later, earlier := now.Add(time.Hour), now.Add(-time.Hour)
+	s.save(t,
+		&storage.Link{ID: 1, LongURL: "https://example.com/soon", NotBefore: &later},
+		&storage.Link{ID: 2, LongURL: "https://example.com/over", NotAfter: &earlier},
+		&storage.Link{ID: 3, LongURL: "https://example.com/now", NotBefore: &earlier, NotAfter: &later},
+	)
+	code := s.h.Codec.Encode
+
+	for _, id := range []int64{1, 2} {
+		if w := s.visit("/"+code(id), uaDesktop, "10.0.0.1"); w.Code != http.StatusNotFound {
+			t.Errorf("link %d outside its window = %d, want 404", id, w.Code)
+		}
+	}
+	if w := s.visit("/"+code(3), uaDesktop, "10.0.0.1"); w.Code != http.StatusFound || w.Header().Get("Location") != "https://example.com/now" {
+		t.Errorf("link inside its window = %d %q, want 302", w.Code, w.Header().Get("Location"))
+	}
+
+	// 窗口外的访问跳转到兜底地址，且不产生点击
+	s.h.FallbackURL = "https://example.com/fallback"
+	s.event(t)
+	if w := s.visit("/"+code(1), uaDesktop, "10.0.0.1"); w.Code != http.StatusFound || w.Header().Get("Location") != s.h.FallbackURL {
+		t.Errorf("inactive link with fallback = %d %q", w.Code, w.Header().Get("Location"))
+	}
+	select {
+	case ev := <-s.events:
+		t.Errorf("unexpected event %+v", ev)
+	default:
+	}
+
+	// 修改窗口后立即按新窗口生效 (缓存的 inactive 标记被清除)
+	w := s.do(t, http.MethodPatch, "/api/links/"+code(1), gin.H{"not_before": nil})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH = %d %s", w.Code, w.Body)
+	}
+	if w := s.visit("/"+code(1), uaDesktop, "10.0.0.1"); w.Header().Get("Location") != "https://example.com/soon" {
+		t.Errorf("after clearing not_before = %d %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestShortenWindow(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		body gin.H
+		want int
+	}{
+		{gin.H{"url": "https://example.com/", "not_before": "2026-05-01T00:00:00Z", "not_after": "2026-06-01T00:00:00Z"}, http.StatusOK},
+		{gin.H{"url": "https://example.com/", "not_before": "2026-06-01T00:00:00Z", "not_after": "2026-05-01T00:00:00Z"}, http.StatusBadRequest},
+		{gin.H{"url": "https://example.com/", "not_after": "tomorrow"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := s.do(t, http.MethodPost, "/shorten", tt.body); w.Code != tt.want {
+			t.Errorf("POST /shorten %v = %d %s, want %d", tt.body, w.Code, w.Body, tt.want)
+		}
+	}
+
+	code := s.shorten(t, gin.H{"url": "https://example.com/", "not_before": "2026-05-01T00:00:00Z"})
+	if w := s.do(t, http.MethodPatch, "/api/links/"+code, gin.H{"not_after": "2026-04-01T00:00:00Z"}); w.Code != http.StatusBadRequest {
+		t.Errorf("PATCH with not_after before the stored not_before = %d, want 400", w.Code)
+	}
+	body := decodeBody(t, s.do(t, http.MethodGet, "/api/links/"+code, nil))
+	if body["not_before"] != "2026-05-01T00:00:00Z" {
+		t.Errorf("GET not_before = %v", body["not_before"])
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	api "github.com/yin1895/tinylink/cmd/tinylink-api/api"
+	"github.com/yin1895/tinylink/internal/config"
+	"github.com/yin1895/tinylink/internal/storage"
+	pb "github.com/yin1895/tinylink/pkg/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// backend 处理请求所需的存储、缓存、过滤器、事件和发号依赖
+type backend struct {
+	store  storage.LinkStore
+	cache  storage.Cache
+	bloom  storage.Filter
+	events storage.EventSink
+	ids    api.IDAllocator
+	keys   storage.APIKeyStore
+	limits storage.RateLimiter
+	usage  storage.UsageStore
+	clicks storage.ClickCounter // 为 nil 时限次链接直接在 store 中计数
+	close  func()
+}
+
+// openStandalone 单机模式：全部依赖都在进程内，无需 MySQL/Redis/Kafka/ID 生成器
+// 数据保存在内存中，进程退出后丢失，适合本地开发、测试和小规模部署
+func openStandalone(cfg *config.Config) *backend {
+	log.Println("Running in standalone mode (in-memory storage, no external services)")
+
+	// 单机模式没有数据库，API Key 由配置文件预置
+	keys := storage.NewMemoryKeyStore()
+	for _, k := range cfg.Auth.Keys {
+		if err := keys.CreateAPIKey(context.Background(), &storage.APIKey{OwnerID: k.Owner, Name: k.Name, Hash: k.Hash}); err != nil {
+			log.Fatalf("Failed to load API key for %s: %v", k.Owner, err)
+		}
+	}
+
+	store := storage.NewMemoryStore()
+	return &backend{
+		store:  store,
+		cache:  storage.NewMemoryCache(),
+		bloom:  storage.NewMemoryBloomFilter(cfg.Bloom.Capacity, cfg.Bloom.FalsePositiveRate),
+		events: storage.LogSink{},
+		ids:    api.NewSequenceIDs(1),
+		keys:   keys,
+		limits: storage.NewMemoryRateLimiter(),
+		usage:  store,
+		close:  func() {},
+	}
+}
+
+// openDistributed 分布式模式：MySQL/PostgreSQL + Redis + Kafka + gRPC ID 生成器
+func openDistributed(cfg *config.Config) *backend {
+	// 1. 初始化数据库 (database.driver 选择 mysql 或 postgres)
+	store, err := storage.OpenSQLStore(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// 2. 初始化 Redis
+	rdb, err := storage.OpenRedis(cfg.Redis)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+
+	// 3. 初始化 Kafka
+	kafkaWriter := storage.NewKafkaWriter(cfg.Kafka)
+
+	// 4. 连接 ID 生成器服务
+	conn, err := grpc.Dial(cfg.IDGenerator.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("Failed to connect to gRPC server: %v", err)
+	}
+
+	return &backend{
+		store:  store,
+		cache:  storage.NewRedisCache(rdb),
+		bloom:  storage.NewBloomFilter(rdb, cfg.Bloom.Key, cfg.Bloom.Capacity, cfg.Bloom.FalsePositiveRate),
+		events: storage.NewKafkaSink(kafkaWriter),
+		ids:    api.NewIDBuffer(pb.NewIdGeneratorClient(conn)),
+		keys:   store,
+		limits: storage.NewRedisRateLimiter(rdb, "tinylink:ratelimit:"),
+		usage:  store,
+		clicks: storage.NewRedisClickCounter(rdb, "tinylink:clicks:"),
+		close: func() {
+			conn.Close()
+			kafkaWriter.Close()
+			rdb.Close()
+			store.Close()
+		},
+	}
+}
@@ -13,83 +13,118 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	api "github.com/yin1895/tinylink/cmd/tinylink-api/api"
 	"github.com/yin1895/tinylink/cmd/tinylink-api/middleware"
+	"github.com/yin1895/tinylink/internal/config"
+	"github.com/yin1895/tinylink/internal/geoip"
 	"github.com/yin1895/tinylink/internal/storage"
-	pb "github.com/yin1895/tinylink/pkg/proto"
 
 	"github.com/gin-gonic/gin"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
-	// 1. 初始化 MySQL
-	if err := storage.InitMySQL(); err != nil {
-		log.Fatalf("Failed to connect to MySQL: %v", err)
+	// 1. 加载配置 (默认值 < 配置文件 < 环境变量 < 命令行参数)
+	cfg, _, err := config.Load("tinylink-api", os.Args[1:])
+	if err != nil {
+		log.Fatalf("Fatal: %v", err)
 	}
-	defer func() {
-		if storage.Db != nil {
-			storage.Db.Close()
-		}
-	}()
 
-	// 2. 初始化 Redis
-	if err := storage.InitRedis(); err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+	// 2. 初始化依赖：mode=standalone 时以单机模式运行
+	var be *backend
+	if cfg.Mode == config.ModeStandalone {
+		be = openStandalone(cfg)
+	} else {
+		be = openDistributed(cfg)
 	}
-	defer func() {
-		if storage.Rdb != nil {
-			storage.Rdb.Close()
-		}
-	}()
+	defer be.close()
 
-	// 3. 初始化 Kafka (新增)
-	storage.InitKafka()
-	defer func() {
-		if storage.KafkaWriter != nil {
-			storage.KafkaWriter.Close()
-		}
-	}()
+	// 3. 启动过期链接清理协程 (过期 24 小时后物理删除，此前访问返回 410)
+	// 后台协程共用 bgCtx，退出时一并停止
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	storage.StartReaper(bgCtx, be.store, time.Minute, 24*time.Hour)
 
-	// 4. 初始化布隆过滤器
-	storage.BF = storage.NewBloomFilter("tinylink:bloom_filter", 1000000, 0.01)
+	// 4. 短码混淆 (可选)：设置 short_code.key 后短码由 ID 经密钥置换生成，不可按顺序枚举
+	codec := api.NewCodec(cfg.ShortCode.Key, cfg.ShortCode.Length, cfg.ShortCode.LegacyMaxID)
 
-	// 5. 连接 ID 生成器服务 (支持环境变量)
-	idServiceAddr := os.Getenv("ID_SERVICE_ADDR")
-	if idServiceAddr == "" {
-		idServiceAddr = "localhost:50051"
-	}
-	conn, err := grpc.Dial(idServiceAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// 5. 对外访问域名：默认域名 + 品牌域名，跳转时按 Host 头区分
+	domains, err := api.NewDomains(cfg.HTTP.BaseURL, cfg.HTTP.Domains)
 	if err != nil {
-		log.Fatalf("Failed to connect to gRPC server: %v", err)
+		log.Fatalf("Fatal: %v", err)
 	}
-	defer conn.Close()
-	api.IdGenClient = pb.NewIdGeneratorClient(conn)
 
-	// 6. 启动 HTTP 服务
+	// GeoIP (可选)：设置 geoip.database 后按访问者国家选择跳转目标，数据库文件更新后自动重新加载
+	geo := openGeoIP(bgCtx, cfg.GeoIP)
+
+	// 6. 套餐额度：按持有者统计用量，额度由配置文件中的套餐定义
+	h := &api.Handler{
+		Store:   be.store,
+		Cache:   be.cache,
+		Bloom:   be.bloom,
+		Events:  be.events,
+		IDs:     be.ids,
+		Codec:   codec,
+		Domains: domains,
+		Dedup:   cfg.Shorten.Dedup,
+		Quotas:  newQuotas(be.usage, cfg.Quota),
+		Clicks:  be.clicks,
+
+		FallbackURL: cfg.Redirect.FallbackURL,
+		Geo:         geo,
+		Queries:     newQueryTemplates(cfg.Query),
+
+		Limits:           be.limits,
+		PasswordAttempts: cfg.RateLimit.Password.Limit,
+		PasswordWindow:   time.Duration(cfg.RateLimit.Password.WindowSeconds) * time.Second,
+	}
+
+	// 7. 启动 HTTP 服务
 	router := gin.Default()
+	// 只信任配置的反向代理转发的客户端 IP，避免伪造 X-Forwarded-For 绕过限流
+	if err := router.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		log.Fatalf("Invalid http.trusted_proxies: %v", err)
+	}
 
 	// (新) 注册监控中间件
 	router.Use(middleware.PrometheusMiddleware())
 
-	router.POST("/shorten", api.ShortenURLHandler)
-	router.GET("/:shortURL", api.RedirectHandler)
+	redirectLimit := rateLimit(be.limits, "redirect", cfg.RateLimit.Redirect)
+	router.GET("/:shortURL", redirectLimit, h.Redirect)
+	router.POST("/:shortURL", redirectLimit, h.Redirect) // 提交受保护链接的密码
+
+	// 生成与管理接口：开启认证时需携带 API Key，链接归属于 key 的持有者
+	authed := router.Group("")
+	if cfg.Auth.Enabled {
+		authed.Use(middleware.APIKeyAuth(be.keys))
+	}
+
+	// 创建接口按持有者限流，并支持 Idempotency-Key，客户端超时重试不会生成重复链接
+	limited := rateLimit(be.limits, "shorten", cfg.RateLimit.Shorten)
+	idempotent := middleware.Idempotency(be.cache)
+	authed.POST("/shorten", limited, idempotent, h.ShortenURL)
+	authed.POST("/shorten/batch", limited, idempotent, h.BatchShorten)
+
+	// 链接管理接口
+	authed.GET("/api/links", h.ListLinks)
+	authed.GET("/api/links/:code", h.GetLink)
+	authed.PATCH("/api/links/:code", h.UpdateLink)
+	authed.DELETE("/api/links/:code", h.DeleteLink)
+	authed.GET("/api/usage", h.Usage)
 
 	// (新) 暴露 Prometheus 指标接口
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	srv := &http.Server{
-		Addr:    ":8080",
+		Addr:    cfg.HTTP.Addr,
 		Handler: router,
 	}
 
 	go func() {
-		log.Println("Starting HTTP server on :8080 ...")
+		log.Printf("Starting HTTP server on %s ...", cfg.HTTP.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("listen: %s\n", err)
 		}
 	}()
 
-	// 7. 优雅停机
+	// 8. 优雅停机
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -103,3 +138,54 @@ func main() {
 	}
 	log.Println("Server exiting")
 }
+
+// openGeoIP 打开 GeoIP 数据库并监视文件更新，未配置时返回 nil
+func openGeoIP(ctx context.Context, cfg config.GeoIPConfig) api.CountryResolver {
+	if cfg.Database == "" {
+		return nil
+	}
+	db, err := geoip.Open(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to open GeoIP database: %v", err)
+	}
+	db.Watch(ctx, time.Duration(cfg.ReloadSeconds)*time.Second)
+	return db
+}
+
+// newQueryTemplates 按配置创建持有者的默认查询参数模板
+func newQueryTemplates(cfg config.QueryConfig) *api.QueryTemplates {
+	template := func(t config.QueryTemplateConfig) api.QueryTemplate {
+		return api.QueryTemplate{Params: t.Params, PassQuery: t.PassQuery}
+	}
+	owners := make(map[string]api.QueryTemplate, len(cfg.Owners))
+	for owner, t := range cfg.Owners {
+		owners[owner] = template(t)
+	}
+	templates, err := api.NewQueryTemplates(owners, template(cfg.Default))
+	if err != nil {
+		log.Fatalf("Invalid query configuration: %v", err)
+	}
+	return templates
+}
+
+// rateLimit 按配置创建限流中间件
+func rateLimit(limiter storage.RateLimiter, name string, p config.RateLimitPolicy) gin.HandlerFunc {
+	return middleware.RateLimit(limiter, middleware.RateLimitPolicy{
+		Name:   name,
+		Limit:  p.Limit,
+		Window: time.Duration(p.WindowSeconds) * time.Second,
+	})
+}
+
+// newQuotas 按配置创建套餐额度
+func newQuotas(usage storage.UsageStore, cfg config.QuotaConfig) *api.Quotas {
+	plan := func(name string) api.Plan {
+		p := cfg.Plans[name]
+		return api.Plan{Name: name, MonthlyLinks: p.MonthlyLinks, ActiveLinks: p.ActiveLinks}
+	}
+	owners := make(map[string]api.Plan, len(cfg.Owners))
+	for owner, name := range cfg.Owners {
+		owners[owner] = plan(name)
+	}
+	return api.NewQuotas(usage, owners, plan(cfg.DefaultPlan))
+}
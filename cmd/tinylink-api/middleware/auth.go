@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yin1895/tinylink/internal/storage"
+)
+
+// APIKeyHeader 除 Authorization: Bearer 外也可用该请求头携带 API Key
+const APIKeyHeader = "X-API-Key"
+
+// ownerKey 认证通过后 key 持有者在 gin.Context 中的键
+const ownerKey = "tinylink.owner"
+
+// APIKeyAuth 校验请求携带的 API Key，通过后记录 key 的持有者供后续处理使用
+// 缺少 key、key 不存在或已吊销时返回 401
+func APIKeyAuth(keys storage.APIKeyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := bearerToken(c.GetHeader("Authorization"))
+		if raw == "" {
+			raw = c.GetHeader(APIKeyHeader)
+		}
+		if raw == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="tinylink"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key required"})
+			return
+		}
+
+		key, err := keys.GetAPIKeyByHash(c.Request.Context(), storage.HashAPIKey(raw))
+		if errors.Is(err, storage.ErrAPIKeyNotFound) || (err == nil && key.Revoked()) {
+			c.Header("WWW-Authenticate", `Bearer realm="tinylink", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify API key"})
+			return
+		}
+
+		c.Set(ownerKey, key.OwnerID)
+		c.Next()
+	}
+}
+
+// OwnerID 返回认证通过的 key 持有者；未启用认证时 ok 为 false
+func OwnerID(c *gin.Context) (owner string, ok bool) {
+	v, ok := c.Get(ownerKey)
+	if !ok {
+		return "", false
+	}
+	return v.(string), true
+}
+
+// bearerToken 解析 Authorization: Bearer <token>
+func bearerToken(header string) string {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yin1895/tinylink/internal/storage"
+)
+
+// failingKeyStore 查询 key 时总是失败
+type failingKeyStore struct {
+	storage.APIKeyStore
+}
+
+func (failingKeyStore) GetAPIKeyByHash(ctx context.Context, hash string) (*storage.APIKey, error) {
+	return nil, errors.New("db down")
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	keys := storage.NewMemoryKeyStore()
+	for _, k := range []*storage.APIKey{
+		{OwnerID: "alice", Hash: storage.HashAPIKey("alice-key")},
+		{OwnerID: "bob", Hash: storage.HashAPIKey("revoked-key")},
+	} {
+		if err := keys.CreateAPIKey(ctx, k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := keys.RevokeAPIKey(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	newRouter := func(store storage.APIKeyStore) *gin.Engine {
+		r := gin.New()
+		r.GET("/", APIKeyAuth(store), func(c *gin.Context) {
+			owner, ok := OwnerID(c)
+			c.JSON(http.StatusOK, gin.H{"owner": owner, "ok": ok})
+		})
+		return r
+	}
+	router := newRouter(keys)
+
+	tests := []struct {
+		name, header, value string
+		want                int
+		challenge           string
+	}{
+		{"no key", "", "", http.StatusUnauthorized, `Bearer realm="tinylink"`},
+		{"bearer", "Authorization", "Bearer alice-key", http.StatusOK, ""},
+		{"bearer is case insensitive", "Authorization", "bearer  alice-key ", http.StatusOK, ""},
+		{"X-API-Key", APIKeyHeader, "alice-key", http.StatusOK, ""},
+		{"basic auth", "Authorization", "Basic YWxpY2U6a2V5", http.StatusUnauthorized, `Bearer realm="tinylink"`},
+		{"unknown key", "Authorization", "Bearer nope", http.StatusUnauthorized, `Bearer realm="tinylink", error="invalid_token"`},
+		{"revoked key", APIKeyHeader, "revoked-key", http.StatusUnauthorized, `Bearer realm="tinylink", error="invalid_token"`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want || w.Header().Get("WWW-Authenticate") != tt.challenge {
+			t.Errorf("%s: %d %q, want %d %q", tt.name, w.Code, w.Header().Get("WWW-Authenticate"), tt.want, tt.challenge)
+		}
+		if tt.want == http.StatusOK && w.Body.String() != `{"ok":true,"owner":"alice"}` {
+			t.Errorf("%s: handler saw %s, want owner alice", tt.name, w.Body)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(APIKeyHeader, "alice-key")
+	w := httptest.NewRecorder()
+	newRouter(failingKeyStore{}).ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("key store error = %d, want 500", w.Code)
+	}
+}
+
+func TestOwnerIDWithoutAuth(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if owner, ok := OwnerID(c); ok || owner != "" {
+		t.Errorf("OwnerID without auth = %q, %v", owner, ok)
+	}
+}
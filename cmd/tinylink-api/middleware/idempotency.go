@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yin1895/tinylink/internal/storage"
+)
+
+const (
+	// IdempotencyHeader 客户端携带的幂等键请求头
+	IdempotencyHeader = "Idempotency-Key"
+	// replayedHeader 标记响应为重放的结果
+	replayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLen = 255
+	// idempotencyTTL 已完成请求的响应保留时长，期间相同的键直接重放
+	idempotencyTTL = 24 * time.Hour
+	// idempotencyLockTTL 处理中标记的有效期，防止进程崩溃后键永久不可用
+	idempotencyLockTTL = time.Minute
+	// idempotencySaveTimeout 请求处理完成后写入或释放记录的超时时间
+	// 客户端此时可能已断开，写入不随请求取消而中断，否则键会一直处于处理中直到锁过期
+	idempotencySaveTimeout = 2 * time.Second
+)
+
+// idempotencyRecord 缓存中保存的幂等记录
+// Status 为 0 表示首个请求仍在处理中
+type idempotencyRecord struct {
+	BodyHash    string `json:"body_hash"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Idempotency 为非幂等的创建接口提供 Idempotency-Key 支持：
+// 首次请求的响应记录在缓存中，相同键与相同请求体的重试直接重放该响应；
+// 同一个键携带不同请求体返回 422，首个请求尚未完成时返回 409
+// 5xx 响应不记录，客户端可使用同一个键重试
+// 缓存不可用时退化为普通请求，不阻断创建
+func Idempotency(cache storage.Cache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idemKey := c.GetHeader(IdempotencyHeader)
+		if idemKey == "" {
+			c.Next()
+			return
+		}
+		if len(idemKey) > maxIdempotencyKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		bodyHash := hex.EncodeToString(sum[:])
+
+		// 键按用户和接口隔离，不同用户或不同接口使用相同的键互不影响
+		ctx := c.Request.Context()
+		owner, _ := OwnerID(c)
+		key := "idempotency:" + owner + ":" + c.Request.Method + ":" + c.FullPath() + ":" + idemKey
+
+		// 1. 抢占处理权，已有记录时按记录响应
+		lock, _ := json.Marshal(idempotencyRecord{BodyHash: bodyHash})
+		acquired, err := cache.SetNX(ctx, key, string(lock), idempotencyLockTTL)
+		if err != nil {
+			log.Printf("idempotency: acquire %q: %v", idemKey, err)
+			c.Next()
+			return
+		}
+		if !acquired {
+			replay(c, cache, key, bodyHash)
+			return
+		}
+
+		// 2. 处理请求并记录响应
+		rec := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+
+		saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencySaveTimeout)
+		defer cancel()
+		status := rec.Status()
+		if status >= http.StatusInternalServerError {
+			if err := cache.Del(saveCtx, key); err != nil {
+				log.Printf("idempotency: release %q: %v", idemKey, err)
+			}
+			return
+		}
+		done, _ := json.Marshal(idempotencyRecord{
+			BodyHash:    bodyHash,
+			Status:      status,
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		})
+		if err := cache.Set(saveCtx, key, string(done), idempotencyTTL); err != nil {
+			log.Printf("idempotency: save %q: %v", idemKey, err)
+		}
+	}
+}
+
+// replay 按已有的幂等记录响应重复请求
+func replay(c *gin.Context, cache storage.Cache, key, bodyHash string) {
+	raw, err := cache.Get(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrCacheMiss) {
+		// 首个请求恰好失败释放了键，让客户端重试
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is in progress"})
+		return
+	}
+	var rec idempotencyRecord
+	if err == nil {
+		err = json.Unmarshal([]byte(raw), &rec)
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to load idempotent response"})
+		return
+	}
+
+	switch {
+	case rec.BodyHash != bodyHash:
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request body"})
+	case rec.Status == 0:
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is in progress"})
+	default:
+		c.Header(replayedHeader, "true")
+		c.Data(rec.Status, rec.ContentType, rec.Body)
+		c.Abort()
+	}
+}
+
+// responseRecorder 在写出响应的同时保留一份响应体
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yin1895/tinylink/internal/storage"
+)
+
+// ctxCache 与 Redis 客户端一样，在 ctx 已取消时拒绝写入
+type ctxCache struct {
+	storage.Cache
+}
+
+func (c ctxCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Cache.Set(ctx, key, value, ttl)
+}
+
+func (c ctxCache) Del(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Cache.Del(ctx, key)
+}
+
+// idempotencyServer 挂载了 Idempotency 的测试服务，handle 决定 POST /shorten 的响应
+type idempotencyServer struct {
+	router *gin.Engine
+	calls  atomic.Int32
+	handle func(c *gin.Context, n int32)
+}
+
+func newIdempotencyServer(t *testing.T) *idempotencyServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	s := &idempotencyServer{handle: func(c *gin.Context, n int32) {
+		c.JSON(http.StatusOK, gin.H{"n": n})
+	}}
+	s.router = gin.New()
+	s.router.POST("/shorten", Idempotency(ctxCache{storage.NewMemoryCache()}), func(c *gin.Context) {
+		s.handle(c, s.calls.Add(1))
+	})
+	return s
+}
+
+func (s *idempotencyServer) post(ctx context.Context, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(body)).WithContext(ctx)
+	if key != "" {
+		req.Header.Set(IdempotencyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	s := newIdempotencyServer(t)
+	ctx := context.Background()
+
+	first := s.post(ctx, "k1", `{"url":"https://example.com/"}`)
+	if first.Code != http.StatusOK || first.Header().Get(replayedHeader) != "" {
+		t.Fatalf("first request: %d %v", first.Code, first.Header())
+	}
+	for range 3 {
+		w := s.post(ctx, "k1", `{"url":"https://example.com/"}`)
+		if w.Code != http.StatusOK || w.Body.String() != first.Body.String() || w.Header().Get(replayedHeader) != "true" {
+			t.Errorf("retry = %d %s %v, want the replayed %s", w.Code, w.Body, w.Header(), first.Body)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Errorf("replayed Content-Type = %q", ct)
+		}
+	}
+	if n := s.calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+
+	// 其他键和不带键的请求正常处理
+	s.post(ctx, "k2", `{"url":"https://example.com/"}`)
+	s.post(ctx, "", `{"url":"https://example.com/"}`)
+	s.post(ctx, "", `{"url":"https://example.com/"}`)
+	if n := s.calls.Load(); n != 4 {
+		t.Errorf("handler ran %d times, want 4", n)
+	}
+}
+
+func TestIdempotencyDifferentBody(t *testing.T) {
+	s := newIdempotencyServer(t)
+	s.post(context.Background(), "k1", `{"url":"https://example.com/a"}`)
+	w := s.post(context.Background(), "k1", `{"url":"https://example.com/b"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key, different body = %d %s, want 422", w.Code, w.Body)
+	}
+	if n := s.calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	s := newIdempotencyServer(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	s.handle = func(c *gin.Context, n int32) {
+		if n == 1 {
+			close(started)
+			<-release
+		}
+		c.JSON(http.StatusOK, gin.H{"n": n})
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- s.post(context.Background(), "k1", `{}`) }()
+	<-started
+
+	if w := s.post(context.Background(), "k1", `{}`); w.Code != http.StatusConflict {
+		t.Errorf("retry while in flight = %d %s, want 409", w.Code, w.Body)
+	}
+	if w := s.post(context.Background(), "k1", `{"other":1}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different body while in flight = %d %s, want 422", w.Code, w.Body)
+	}
+	close(release)
+	if w := <-done; w.Code != http.StatusOK {
+		t.Fatalf("first request = %d %s", w.Code, w.Body)
+	}
+	if w := s.post(context.Background(), "k1", `{}`); w.Code != http.StatusOK || w.Header().Get(replayedHeader) != "true" {
+		t.Errorf("retry after completion = %d %v, want a replay", w.Code, w.Header())
+	}
+}
+
+func TestIdempotencyServerError(t *testing.T) {
+	s := newIdempotencyServer(t)
+	s.handle = func(c *gin.Context, n int32) {
+		status := http.StatusOK
+		if n == 1 {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"n": n})
+	}
+	if w := s.post(context.Background(), "k1", `{}`); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("first request = %d", w.Code)
+	}
+	// 5xx 不记录，使用同一个键重试时重新处理
+	if w := s.post(context.Background(), "k1", `{}`); w.Code != http.StatusOK || w.Body.String() != `{"n":2}` {
+		t.Errorf("retry after 5xx = %d %s, want 200 {\"n\":2}", w.Code, w.Body)
+	}
+}
+
+// TestIdempotencyClientGone 客户端在处理期间断开 (请求 ctx 被取消) 时仍然记录或释放键，
+// 否则重试会一直收到 409 直到处理中标记过期
+func TestIdempotencyClientGone(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusInternalServerError} {
+		s := newIdempotencyServer(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		s.handle = func(c *gin.Context, n int32) {
+			if n == 1 {
+				cancel()
+				c.JSON(status, gin.H{"n": n})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"n": n})
+		}
+		s.post(ctx, "k1", `{}`)
+
+		w := s.post(context.Background(), "k1", `{}`)
+		want := `{"n":1}` // 已记录的响应被重放
+		if status >= http.StatusInternalServerError {
+			want = `{"n":2}` // 键已释放，重新处理
+		}
+		if w.Code == http.StatusConflict || w.Body.String() != want {
+			t.Errorf("first response %d, client gone: retry = %d %s, want %s", status, w.Code, w.Body, want)
+		}
+	}
+}
+
+func TestIdempotencyKeyTooLong(t *testing.T) {
+	s := newIdempotencyServer(t)
+	if w := s.post(context.Background(), strings.Repeat("k", maxIdempotencyKeyLen+1), `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("long key = %d, want 400", w.Code)
+	}
+	if w := s.post(context.Background(), strings.Repeat("k", maxIdempotencyKeyLen), `{}`); w.Code != http.StatusOK {
+		t.Errorf("key of %d characters = %d, want 200", maxIdempotencyKeyLen, w.Code)
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/yin1895/tinylink/internal/storage"
+)
+
+// 被限流拒绝的请求数
+// 标签: policy(shorten/redirect)
+var rateLimitRejectedTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "rate_limit_rejected_total",
+		Help: "Total number of requests rejected by rate limiting",
+	},
+	[]string{"policy"},
+)
+
+// RateLimitPolicy 限流策略：任意 Window 时长内同一客户端最多 Limit 次请求
+type RateLimitPolicy struct {
+	Name   string // 策略名，用于区分计数和指标标签
+	Limit  int    // 0 表示不限流
+	Window time.Duration
+}
+
+// RateLimit 按策略限流，客户端以 API Key 持有者区分 (未认证时按 IP)
+// 超限返回 429 并通过 Retry-After 告知需等待的秒数；限流器不可用时放行请求
+func RateLimit(limiter storage.RateLimiter, policy RateLimitPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if policy.Limit <= 0 {
+			c.Next()
+			return
+		}
+
+		client := "ip:" + c.ClientIP()
+		if owner, ok := OwnerID(c); ok {
+			client = "owner:" + owner
+		}
+		res, err := limiter.Allow(c.Request.Context(), policy.Name+":"+client, policy.Limit, policy.Window)
+		if err != nil {
+			log.Printf("rate limit %s: %v", policy.Name, err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(policy.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		if !res.Allowed {
+			retryAfter := max(int(math.Ceil(res.RetryAfter.Seconds())), 1)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			rateLimitRejectedTotal.WithLabelValues(policy.Name).Inc()
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
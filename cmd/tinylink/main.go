@@ -0,0 +1,223 @@
+// cmd/tinylink/main.go
+// tinylink 运维命令行工具
+//
+// 用法:
+//
+//	tinylink [flags] migrate up                   执行所有未执行的迁移
+//	tinylink [flags] migrate down [N]             回滚最近 N 个迁移 (默认 1)
+//	tinylink [flags] migrate status               查看迁移状态
+//	tinylink [flags] apikey create OWNER [NAME]   创建 API Key (明文只输出一次)
+//	tinylink [flags] apikey list                  列出 API Key
+//	tinylink [flags] apikey revoke ID             吊销 API Key
+//	tinylink apikey generate                      生成 key 及其哈希，供单机模式配置 auth.keys
+//
+// flags 与 tinylink-api 相同 (如 -config、-db-driver)，数据库连接配置同样可通过配置文件或环境变量指定
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yin1895/tinylink/internal/config"
+	"github.com/yin1895/tinylink/internal/migrations"
+	"github.com/yin1895/tinylink/internal/storage"
+)
+
+const usage = `usage:
+  tinylink [flags] migrate up                   apply all pending migrations
+  tinylink [flags] migrate down [N]             roll back the last N migrations (default 1)
+  tinylink [flags] migrate status               show migration status
+  tinylink [flags] apikey create OWNER [NAME]   create an API key (printed only once)
+  tinylink [flags] apikey list                  list API keys
+  tinylink [flags] apikey revoke ID             revoke an API key
+  tinylink apikey generate                      print a new key and its hash for auth.keys`
+
+func main() {
+	cfg, args, err := config.Load("tinylink", os.Args[1:])
+	if err != nil {
+		log.Fatalf("Fatal: %v", err)
+	}
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "migrate":
+		err = migrate(context.Background(), cfg.Database, args[1], args[2:])
+	case "apikey":
+		err = apikey(context.Background(), cfg.Database, args[1], args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("Fatal: %v", err)
+	}
+}
+
+// migrate 执行 migrate 子命令
+func migrate(ctx context.Context, dbConfig config.DatabaseConfig, cmd string, args []string) error {
+	steps := 1
+	switch cmd {
+	case "up", "status":
+		if len(args) > 0 {
+			return fmt.Errorf("unexpected arguments: %v\n%s", args, usage)
+		}
+	case "down":
+		if len(args) > 1 {
+			return fmt.Errorf("unexpected arguments: %v\n%s", args[1:], usage)
+		}
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step count %q", args[0])
+			}
+			steps = n
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", cmd, usage)
+	}
+
+	db, err := storage.OpenDB(dbConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	m, err := migrations.New(db, dbConfig.Driver)
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "up":
+		done, err := m.Up(ctx)
+		for _, mig := range done {
+			log.Printf("Applied %04d_%s", mig.Version, mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			log.Println("Schema is up to date.")
+		}
+	case "down":
+		done, err := m.Down(ctx, steps)
+		for _, mig := range done {
+			log.Printf("Rolled back %04d_%s", mig.Version, mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			log.Println("No applied migrations to roll back.")
+		}
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, st := range statuses {
+			state, appliedAt := "pending", "-"
+			if st.Applied {
+				state, appliedAt = "applied", st.AppliedAt.Format(time.RFC3339)
+			}
+			if st.Modified {
+				state = "modified"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+		}
+		return w.Flush()
+	}
+	return nil
+}
+
+// apikey 执行 apikey 子命令
+func apikey(ctx context.Context, dbConfig config.DatabaseConfig, cmd string, args []string) error {
+	var id int64
+	switch cmd {
+	case "generate":
+		if len(args) > 0 {
+			return fmt.Errorf("unexpected arguments: %v\n%s", args, usage)
+		}
+		key, hash, err := storage.GenerateAPIKey()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("key:  %s\nhash: %s\n", key, hash)
+		return nil
+	case "create":
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("apikey create requires OWNER and an optional NAME\n%s", usage)
+		}
+		if len(args[0]) > 64 {
+			return fmt.Errorf("owner must be at most 64 characters")
+		}
+	case "list":
+		if len(args) > 0 {
+			return fmt.Errorf("unexpected arguments: %v\n%s", args, usage)
+		}
+	case "revoke":
+		if len(args) != 1 {
+			return fmt.Errorf("apikey revoke requires ID\n%s", usage)
+		}
+		n, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid key id %q", args[0])
+		}
+		id = n
+	default:
+		return fmt.Errorf("unknown apikey command %q\n%s", cmd, usage)
+	}
+
+	store, err := storage.OpenSQLStore(dbConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer store.Close()
+
+	switch cmd {
+	case "create":
+		key, hash, err := storage.GenerateAPIKey()
+		if err != nil {
+			return err
+		}
+		k := &storage.APIKey{OwnerID: args[0], Hash: hash}
+		if len(args) == 2 {
+			k.Name = args[1]
+		}
+		if err := store.CreateAPIKey(ctx, k); err != nil {
+			return err
+		}
+		log.Printf("Created API key %d for %s. Store it now, it cannot be shown again.", k.ID, k.OwnerID)
+		fmt.Println(key)
+	case "list":
+		keys, err := store.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tOWNER\tNAME\tCREATED AT\tREVOKED AT")
+		for _, k := range keys {
+			revokedAt := "-"
+			if k.Revoked() {
+				revokedAt = k.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", k.ID, k.OwnerID, k.Name, k.CreatedAt.Format(time.RFC3339), revokedAt)
+		}
+		return w.Flush()
+	case "revoke":
+		if err := store.RevokeAPIKey(ctx, id); err != nil {
+			return err
+		}
+		log.Printf("Revoked API key %d", id)
+	}
+	return nil
+}
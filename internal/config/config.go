@@ -0,0 +1,333 @@
+// Package config 集中管理 tinylink 各服务的配置
+//
+// 配置按以下优先级 (由低到高) 合并：内置默认值、配置文件 (YAML / TOML)、
+// 环境变量、命令行参数。合并后统一校验，校验失败时服务拒绝启动。
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+)
+
+// 运行模式
+const (
+	ModeDistributed = "distributed" // MySQL/PostgreSQL + Redis + Kafka + gRPC ID 生成器
+	ModeStandalone  = "standalone"  // 全部依赖在进程内
+)
+
+// Config tinylink-api 与 id-generator 共用的配置
+type Config struct {
+	Mode        string            `yaml:"mode" toml:"mode"`
+	HTTP        HTTPConfig        `yaml:"http" toml:"http"`
+	Database    DatabaseConfig    `yaml:"database" toml:"database"`
+	Redis       RedisConfig       `yaml:"redis" toml:"redis"`
+	Kafka       KafkaConfig       `yaml:"kafka" toml:"kafka"`
+	Bloom       BloomConfig       `yaml:"bloom" toml:"bloom"`
+	IDGenerator IDGeneratorConfig `yaml:"id_generator" toml:"id_generator"`
+	ShortCode   ShortCodeConfig   `yaml:"short_code" toml:"short_code"`
+	Shorten     ShortenConfig     `yaml:"shorten" toml:"shorten"`
+	Redirect    RedirectConfig    `yaml:"redirect" toml:"redirect"`
+	GeoIP       GeoIPConfig       `yaml:"geoip" toml:"geoip"`
+	Query       QueryConfig       `yaml:"query" toml:"query"`
+	Auth        AuthConfig        `yaml:"auth" toml:"auth"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit" toml:"rate_limit"`
+	Quota       QuotaConfig       `yaml:"quota" toml:"quota"`
+}
+
+// HTTPConfig API 服务监听地址及对外访问地址
+type HTTPConfig struct {
+	Addr    string   `yaml:"addr" toml:"addr"`
+	BaseURL string   `yaml:"base_url" toml:"base_url"` // 默认域名拼接短链接的前缀，如 https://tiny.link (末尾不含 '/')
+	Domains []string `yaml:"domains" toml:"domains"`   // 额外的品牌域名访问地址，如 https://go.brand.com
+	// TrustedProxies 可信反向代理的 IP 或 CIDR，仅信任来自这些地址的 X-Forwarded-For
+	// 为空时直接使用连接的对端地址作为客户端 IP
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+}
+
+// DatabaseConfig 数据库连接配置
+// 设置 DSN 时直接使用，否则由 Host/User/Password/Name 按驱动拼接
+type DatabaseConfig struct {
+	Driver   string `yaml:"driver" toml:"driver"` // mysql 或 postgres
+	DSN      string `yaml:"dsn" toml:"dsn"`
+	Host     string `yaml:"host" toml:"host"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+}
+
+// RedisConfig Redis 连接配置
+type RedisConfig struct {
+	Addr     string `yaml:"addr" toml:"addr"`
+	Password string `yaml:"password" toml:"password"`
+	DB       int    `yaml:"db" toml:"db"`
+}
+
+// KafkaConfig 点击事件 Kafka 配置
+type KafkaConfig struct {
+	Broker string `yaml:"broker" toml:"broker"`
+	Topic  string `yaml:"topic" toml:"topic"`
+}
+
+// BloomConfig 短码布隆过滤器配置
+type BloomConfig struct {
+	Key               string  `yaml:"key" toml:"key"` // Redis 中的 key
+	Capacity          uint    `yaml:"capacity" toml:"capacity"`
+	FalsePositiveRate float64 `yaml:"false_positive_rate" toml:"false_positive_rate"`
+}
+
+// IDGeneratorConfig ID 生成器配置
+type IDGeneratorConfig struct {
+	ListenAddr  string `yaml:"listen_addr" toml:"listen_addr"` // id-generator 监听地址
+	Addr        string `yaml:"addr" toml:"addr"`               // tinylink-api 连接的地址
+	SegmentStep int64  `yaml:"segment_step" toml:"segment_step"`
+}
+
+// ShortCodeConfig 短码混淆配置，Key 为空时使用顺序编码
+type ShortCodeConfig struct {
+	Key    string `yaml:"key" toml:"key"`
+	Length int    `yaml:"length" toml:"length"`
+	// LegacyMaxID 启用 Key 前已发放的最大 ID，这些链接的顺序短码继续有效；0 表示不接受顺序短码
+	LegacyMaxID int64 `yaml:"legacy_max_id" toml:"legacy_max_id"`
+}
+
+// ShortenConfig 生成短链接的行为
+type ShortenConfig struct {
+	// Dedup 开启后同一域名下同一用户的相同长链接 (无别名、不过期) 复用已有短码
+	Dedup bool `yaml:"dedup" toml:"dedup"`
+}
+
+// RedirectConfig 短链接跳转的行为
+type RedirectConfig struct {
+	// FallbackURL 访问不在生效时间窗口 (not_before / not_after) 内的链接时跳转的地址，为空时返回 404
+	FallbackURL string `yaml:"fallback_url" toml:"fallback_url"`
+}
+
+// GeoIPConfig 按访问者国家跳转所用的 GeoIP 数据库
+type GeoIPConfig struct {
+	// Database MaxMind 格式 (MMDB) 的国家或城市数据库文件路径，如 GeoLite2-Country.mmdb，为空时不启用
+	Database string `yaml:"database" toml:"database"`
+	// ReloadSeconds 检查数据库文件是否更新的间隔，文件变化后自动重新加载
+	ReloadSeconds int `yaml:"reload_seconds" toml:"reload_seconds"`
+}
+
+// QueryConfig 跳转时合并到目标地址的默认查询参数 (如 UTM)，链接上的设置优先
+// 未启用认证时所有链接的持有者均为空字符串，使用 default 模板
+type QueryConfig struct {
+	Default QueryTemplateConfig            `yaml:"default" toml:"default"` // 未在 owners 中列出的持有者使用的模板
+	Owners  map[string]QueryTemplateConfig `yaml:"owners" toml:"owners"`   // 持有者 -> 模板
+}
+
+// QueryTemplateConfig 查询参数模板
+type QueryTemplateConfig struct {
+	// Params 参数名 -> 值，值中可使用 {code}、{domain}、{platform}、{country}、{variant} 占位符
+	Params map[string]string `yaml:"params" toml:"params"`
+	// PassQuery 链接未单独设置时，是否把访客附加在短链接上的查询参数透传给目标地址
+	PassQuery bool `yaml:"pass_query" toml:"pass_query"`
+}
+
+// AuthConfig API Key 认证配置
+// 开启后生成短链接和管理接口需携带 API Key，链接归属于 key 的持有者，跳转接口不受影响
+type AuthConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// Keys 单机模式下预置的 key (单机模式没有数据库，无法通过 tinylink apikey 创建)
+	Keys []StaticAPIKey `yaml:"keys" toml:"keys"`
+}
+
+// StaticAPIKey 配置文件中预置的 API Key，只保存哈希 (由 tinylink apikey generate 生成)
+type StaticAPIKey struct {
+	Owner string `yaml:"owner" toml:"owner"`
+	Name  string `yaml:"name" toml:"name"`
+	Hash  string `yaml:"hash" toml:"hash"` // key 的 SHA-256 (十六进制)
+}
+
+// RateLimitConfig 按客户端 (API Key 持有者，未认证时为 IP) 限流，生成与跳转使用独立的策略
+type RateLimitConfig struct {
+	Shorten  RateLimitPolicy `yaml:"shorten" toml:"shorten"`   // POST /shorten 与 /shorten/batch
+	Redirect RateLimitPolicy `yaml:"redirect" toml:"redirect"` // GET /:code
+	// Password 同一客户端对同一受密码保护链接的密码尝试次数，防止暴力破解
+	Password RateLimitPolicy `yaml:"password" toml:"password"`
+}
+
+// RateLimitPolicy 任意 WindowSeconds 秒内最多 Limit 次请求，Limit 为 0 表示不限流
+type RateLimitPolicy struct {
+	Limit         int `yaml:"limit" toml:"limit"`
+	WindowSeconds int `yaml:"window_seconds" toml:"window_seconds"`
+}
+
+// QuotaConfig 按链接持有者 (API Key 的 owner，未启用认证时为空字符串) 分配套餐
+type QuotaConfig struct {
+	// DefaultPlan 未在 Owners 中指定套餐的持有者使用的套餐，为空表示不限额
+	DefaultPlan string                `yaml:"default_plan" toml:"default_plan"`
+	Plans       map[string]PlanConfig `yaml:"plans" toml:"plans"`
+	Owners      map[string]string     `yaml:"owners" toml:"owners"` // 持有者 -> 套餐名
+}
+
+// PlanConfig 套餐的额度，0 表示不限
+type PlanConfig struct {
+	MonthlyLinks int64 `yaml:"monthly_links" toml:"monthly_links"` // 每个自然月 (UTC) 可新建的链接数
+	ActiveLinks  int64 `yaml:"active_links" toml:"active_links"`   // 同时存在的未过期链接数
+}
+
+// Default 返回本地开发使用的默认配置 (与 docker-compose 中的服务对应)
+func Default() *Config {
+	return &Config{
+		Mode: ModeDistributed,
+		HTTP: HTTPConfig{
+			Addr:    ":8080",
+			BaseURL: "http://localhost:8080",
+		},
+		Database: DatabaseConfig{
+			Driver: "mysql",
+			Name:   "tinylink",
+		},
+		Redis: RedisConfig{
+			Addr: "localhost:6379",
+		},
+		Kafka: KafkaConfig{
+			Broker: "localhost:9092",
+			Topic:  "link_clicks",
+		},
+		Bloom: BloomConfig{
+			Key:               "tinylink:bloom_filter",
+			Capacity:          1000000,
+			FalsePositiveRate: 0.01,
+		},
+		IDGenerator: IDGeneratorConfig{
+			ListenAddr:  ":50051",
+			Addr:        "localhost:50051",
+			SegmentStep: 10000,
+		},
+		ShortCode: ShortCodeConfig{
+			Length: 7,
+		},
+		GeoIP: GeoIPConfig{
+			ReloadSeconds: 60,
+		},
+		RateLimit: RateLimitConfig{
+			Shorten:  RateLimitPolicy{WindowSeconds: 60},
+			Redirect: RateLimitPolicy{WindowSeconds: 60},
+			Password: RateLimitPolicy{Limit: 5, WindowSeconds: 900},
+		},
+	}
+}
+
+// Validate 校验配置，返回所有不合法的项
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Mode == ModeDistributed || c.Mode == ModeStandalone,
+		"mode must be %q or %q, got %q", ModeDistributed, ModeStandalone, c.Mode)
+	check(c.HTTP.Addr != "", "http.addr is required")
+	if !isBaseURL(c.HTTP.BaseURL) {
+		errs = append(errs, fmt.Errorf("http.base_url must be an absolute http(s) URL, got %q", c.HTTP.BaseURL))
+	}
+	for _, d := range c.HTTP.Domains {
+		if u, err := url.Parse(d); err != nil || !isBaseURL(d) || (u.Path != "" && u.Path != "/") {
+			errs = append(errs, fmt.Errorf("http.domains entries must be http(s) URLs without a path, got %q", d))
+		}
+	}
+	check(c.Database.Driver == "mysql" || c.Database.Driver == "postgres",
+		"database.driver must be mysql or postgres, got %q", c.Database.Driver)
+	check(c.Database.DSN != "" || c.Database.Name != "", "database.name is required when database.dsn is not set")
+	check(c.Redis.Addr != "", "redis.addr is required")
+	check(c.Redis.DB >= 0, "redis.db must not be negative")
+	check(c.Kafka.Broker != "", "kafka.broker is required")
+	check(c.Kafka.Topic != "", "kafka.topic is required")
+	check(c.Bloom.Key != "", "bloom.key is required")
+	check(c.Bloom.Capacity > 0, "bloom.capacity must be positive")
+	check(c.Bloom.FalsePositiveRate > 0 && c.Bloom.FalsePositiveRate < 1,
+		"bloom.false_positive_rate must be between 0 and 1, got %v", c.Bloom.FalsePositiveRate)
+	check(c.IDGenerator.ListenAddr != "", "id_generator.listen_addr is required")
+	check(c.IDGenerator.Addr != "", "id_generator.addr is required")
+	check(c.IDGenerator.SegmentStep > 0, "id_generator.segment_step must be positive")
+	check(c.ShortCode.Length >= 4 && c.ShortCode.Length <= 10,
+		"short_code.length must be between 4 and 10, got %d", c.ShortCode.Length)
+	check(c.ShortCode.LegacyMaxID >= 0, "short_code.legacy_max_id must not be negative")
+	if c.Redirect.FallbackURL != "" && !isBaseURL(c.Redirect.FallbackURL) {
+		errs = append(errs, fmt.Errorf("redirect.fallback_url must be an absolute http(s) URL, got %q", c.Redirect.FallbackURL))
+	}
+	check(c.GeoIP.ReloadSeconds > 0, "geoip.reload_seconds must be positive")
+	for _, p := range []struct {
+		name   string
+		policy RateLimitPolicy
+	}{{"shorten", c.RateLimit.Shorten}, {"redirect", c.RateLimit.Redirect}, {"password", c.RateLimit.Password}} {
+		check(p.policy.Limit >= 0, "rate_limit.%s.limit must not be negative", p.name)
+		check(p.policy.WindowSeconds > 0, "rate_limit.%s.window_seconds must be positive", p.name)
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Quota.Plans)) {
+		p := c.Quota.Plans[name]
+		check(p.MonthlyLinks >= 0 && p.ActiveLinks >= 0, "quota.plans.%s limits must not be negative", name)
+	}
+	if _, ok := c.Quota.Plans[c.Quota.DefaultPlan]; !ok && c.Quota.DefaultPlan != "" {
+		errs = append(errs, fmt.Errorf("quota.default_plan %q is not defined in quota.plans", c.Quota.DefaultPlan))
+	}
+	for _, owner := range slices.Sorted(maps.Keys(c.Quota.Owners)) {
+		plan := c.Quota.Owners[owner]
+		_, ok := c.Quota.Plans[plan]
+		check(ok, "quota.owners.%s: plan %q is not defined in quota.plans", owner, plan)
+	}
+	check(len(c.Auth.Keys) == 0 || c.Mode == ModeStandalone,
+		"auth.keys is only supported in standalone mode, use `tinylink apikey create` instead")
+	for i, k := range c.Auth.Keys {
+		check(k.Owner != "" && len(k.Owner) <= 64, "auth.keys[%d].owner must be 1-64 characters", i)
+		check(isSHA256(k.Hash), "auth.keys[%d].hash must be a hex-encoded SHA-256", i)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ConnString 返回数据库驱动使用的连接串
+// 未显式配置时按驱动使用 docker-compose 中的开发环境账号
+func (d DatabaseConfig) ConnString() string {
+	if d.DSN != "" {
+		return d.DSN
+	}
+	switch d.Driver {
+	case "postgres":
+		u := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(or(d.User, "postgres"), or(d.Password, "postgres_password")),
+			Host:     or(d.Host, "127.0.0.1:5432"),
+			Path:     "/" + d.Name,
+			RawQuery: "sslmode=disable",
+		}
+		return u.String()
+	default:
+		return fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True",
+			or(d.User, "root"), or(d.Password, "root_password"), or(d.Host, "127.0.0.1:33061"), d.Name)
+	}
+}
+
+// isBaseURL 判断是否为带 host 的 http(s) 地址
+func isBaseURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isSHA256 判断是否为小写十六进制的 SHA-256
+func isSHA256(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func or(v, fallback string) string {
+	if v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// setting 一个可由环境变量和命令行参数覆盖的配置项
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	value flag.Value
+}
+
+// settings 返回 cfg 中可覆盖的配置项，value 直接写入 cfg 对应字段
+func settings(cfg *Config) []setting {
+	return []setting{
+		{"mode", "TINYLINK_MODE", "run mode: distributed or standalone", (*stringValue)(&cfg.Mode)},
+		{"http-addr", "HTTP_ADDR", "HTTP listen address", (*stringValue)(&cfg.HTTP.Addr)},
+		{"base-url", "BASE_URL", "public base URL for short links", (*stringValue)(&cfg.HTTP.BaseURL)},
+		{"domains", "DOMAINS", "comma-separated base URLs of additional branded domains", (*stringsValue)(&cfg.HTTP.Domains)},
+		{"trusted-proxies", "TRUSTED_PROXIES", "comma-separated IPs or CIDRs of reverse proxies trusted for X-Forwarded-For", (*stringsValue)(&cfg.HTTP.TrustedProxies)},
+		{"db-driver", "DB_DRIVER", "database driver: mysql or postgres", (*stringValue)(&cfg.Database.Driver)},
+		{"db-dsn", "DATABASE_URL", "database connection string (overrides db-host/user/password/name)", (*stringValue)(&cfg.Database.DSN)},
+		{"db-host", "DB_HOST", "database host:port", (*stringValue)(&cfg.Database.Host)},
+		{"db-user", "DB_USER", "database user", (*stringValue)(&cfg.Database.User)},
+		{"db-password", "DB_PASSWORD", "database password", (*stringValue)(&cfg.Database.Password)},
+		{"db-name", "DB_NAME", "database name", (*stringValue)(&cfg.Database.Name)},
+		{"redis-addr", "REDIS_HOST", "Redis host:port", (*stringValue)(&cfg.Redis.Addr)},
+		{"redis-password", "REDIS_PASSWORD", "Redis password", (*stringValue)(&cfg.Redis.Password)},
+		{"redis-db", "REDIS_DB", "Redis database number", (*intValue)(&cfg.Redis.DB)},
+		{"kafka-broker", "KAFKA_BROKER", "Kafka broker host:port", (*stringValue)(&cfg.Kafka.Broker)},
+		{"kafka-topic", "KAFKA_TOPIC", "Kafka topic for click events", (*stringValue)(&cfg.Kafka.Topic)},
+		{"bloom-key", "BLOOM_KEY", "Redis key of the short code Bloom filter", (*stringValue)(&cfg.Bloom.Key)},
+		{"bloom-capacity", "BLOOM_CAPACITY", "expected number of short codes in the Bloom filter", (*uintValue)(&cfg.Bloom.Capacity)},
+		{"bloom-fp-rate", "BLOOM_FP_RATE", "Bloom filter false positive rate", (*floatValue)(&cfg.Bloom.FalsePositiveRate)},
+		{"id-listen-addr", "ID_LISTEN_ADDR", "id-generator gRPC listen address", (*stringValue)(&cfg.IDGenerator.ListenAddr)},
+		{"id-service-addr", "ID_SERVICE_ADDR", "id-generator address used by the API", (*stringValue)(&cfg.IDGenerator.Addr)},
+		{"id-segment-step", "ID_SEGMENT_STEP", "number of IDs reserved per database round trip", (*int64Value)(&cfg.IDGenerator.SegmentStep)},
+		{"short-code-key", "SHORT_CODE_KEY", "secret key for short code obfuscation (empty: sequential codes)", (*stringValue)(&cfg.ShortCode.Key)},
+		{"short-code-length", "SHORT_CODE_LENGTH", "length of obfuscated short codes (4-10)", (*intValue)(&cfg.ShortCode.Length)},
+		{"short-code-legacy-max-id", "SHORT_CODE_LEGACY_MAX_ID", "largest ID issued before short-code-key was set; its sequential codes stay valid", (*int64Value)(&cfg.ShortCode.LegacyMaxID)},
+		{"dedup", "SHORTEN_DEDUP", "reuse the existing short code when the same URL is shortened again", (*boolValue)(&cfg.Shorten.Dedup)},
+		{"redirect-fallback-url", "REDIRECT_FALLBACK_URL", "URL to redirect to when a link is outside its activation window (empty: 404)", (*stringValue)(&cfg.Redirect.FallbackURL)},
+		{"geoip-db", "GEOIP_DATABASE", "path of a MaxMind (MMDB) country database for geo-targeted links (empty: disabled)", (*stringValue)(&cfg.GeoIP.Database)},
+		{"geoip-reload-interval", "GEOIP_RELOAD_INTERVAL", "seconds between checks for an updated GeoIP database file", (*intValue)(&cfg.GeoIP.ReloadSeconds)},
+		{"query-pass-through", "QUERY_PASS_THROUGH", "pass query strings appended to short URLs through to the destination by default", (*boolValue)(&cfg.Query.Default.PassQuery)},
+		{"auth", "AUTH_ENABLED", "require an API key to create and manage links", (*boolValue)(&cfg.Auth.Enabled)},
+		{"rate-limit-shorten", "RATE_LIMIT_SHORTEN", "shorten requests allowed per client per window (0: unlimited)", (*intValue)(&cfg.RateLimit.Shorten.Limit)},
+		{"rate-limit-shorten-window", "RATE_LIMIT_SHORTEN_WINDOW", "shorten rate limit window in seconds", (*intValue)(&cfg.RateLimit.Shorten.WindowSeconds)},
+		{"rate-limit-redirect", "RATE_LIMIT_REDIRECT", "redirects allowed per client per window (0: unlimited)", (*intValue)(&cfg.RateLimit.Redirect.Limit)},
+		{"rate-limit-redirect-window", "RATE_LIMIT_REDIRECT_WINDOW", "redirect rate limit window in seconds", (*intValue)(&cfg.RateLimit.Redirect.WindowSeconds)},
+		{"rate-limit-password", "RATE_LIMIT_PASSWORD", "password attempts allowed per client per protected link per window (0: unlimited)", (*intValue)(&cfg.RateLimit.Password.Limit)},
+		{"rate-limit-password-window", "RATE_LIMIT_PASSWORD_WINDOW", "password attempt limit window in seconds", (*intValue)(&cfg.RateLimit.Password.WindowSeconds)},
+		{"quota-default-plan", "QUOTA_DEFAULT_PLAN", "quota plan for owners without an assigned plan (empty: unlimited)", (*stringValue)(&cfg.Quota.DefaultPlan)},
+	}
+}
+
+// Load 加载 name 服务的配置，args 为命令行参数 (不含程序名)
+// 配置文件由 -config 参数或环境变量 TINYLINK_CONFIG 指定，按扩展名识别 YAML / TOML
+// 返回合并校验后的配置，以及剩余的非 flag 参数
+func Load(name string, args []string) (*Config, []string, error) {
+	// 1. 解析命令行参数，先记录下来，待文件和环境变量合并后再覆盖
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	configFile := fs.String("config", os.Getenv("TINYLINK_CONFIG"), "path to a YAML or TOML config file (env TINYLINK_CONFIG)")
+	flags := make(map[string]string)
+	for _, s := range settings(Default()) {
+		_, isBool := s.value.(interface{ IsBoolFlag() bool })
+		fs.Var(&recordedValue{name: s.flag, flags: flags, def: s.value.String(), isBool: isBool}, s.flag,
+			fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	// 2. 默认值 < 配置文件
+	cfg := Default()
+	if *configFile != "" {
+		if err := loadFile(*configFile, cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// 3. 配置文件 < 环境变量 < 命令行参数
+	for _, s := range settings(cfg) {
+		if v, ok := os.LookupEnv(s.env); ok && v != "" {
+			if err := s.value.Set(v); err != nil {
+				return nil, nil, fmt.Errorf("invalid %s %q: %w", s.env, v, err)
+			}
+		}
+		if v, ok := flags[s.flag]; ok {
+			if err := s.value.Set(v); err != nil {
+				return nil, nil, fmt.Errorf("invalid -%s %q: %w", s.flag, v, err)
+			}
+		}
+	}
+
+	cfg.HTTP.BaseURL = strings.TrimRight(cfg.HTTP.BaseURL, "/")
+	for i, d := range cfg.HTTP.Domains {
+		cfg.HTTP.Domains[i] = strings.TrimRight(d, "/")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, fs.Args(), nil
+}
+
+// loadFile 读取配置文件覆盖 cfg，文件中的未知字段视为错误
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalWithOptions(data, cfg, yaml.DisallowUnknownField())
+	case ".toml":
+		err = toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields().Decode(cfg)
+	default:
+		return fmt.Errorf("unsupported config file %q: must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// recordedValue 记录命令行参数的原始值，由 Load 在最后阶段写入配置
+type recordedValue struct {
+	name   string
+	flags  map[string]string
+	def    string
+	isBool bool
+}
+
+func (v *recordedValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.def
+}
+
+func (v *recordedValue) Set(s string) error {
+	v.flags[v.name] = s
+	return nil
+}
+
+func (v *recordedValue) IsBoolFlag() bool { return v.isBool }
+
+type stringValue string
+
+func (v *stringValue) String() string { return string(*v) }
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+// stringsValue 逗号分隔的字符串列表
+type stringsValue []string
+
+func (v *stringsValue) String() string { return strings.Join(*v, ",") }
+
+func (v *stringsValue) Set(s string) error {
+	*v = nil
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*v = append(*v, item)
+		}
+	}
+	return nil
+}
+
+type boolValue bool
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return errors.New("must be true or false")
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+// IsBoolFlag 布尔参数可省略取值，如 -dedup 等同于 -dedup=true
+func (v *boolValue) IsBoolFlag() bool { return true }
+
+type intValue int
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return errors.New("must be an integer")
+	}
+	*v = intValue(n)
+	return nil
+}
+
+type int64Value int64
+
+func (v *int64Value) String() string { return strconv.FormatInt(int64(*v), 10) }
+
+func (v *int64Value) Set(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return errors.New("must be an integer")
+	}
+	*v = int64Value(n)
+	return nil
+}
+
+type uintValue uint
+
+func (v *uintValue) String() string { return strconv.FormatUint(uint64(*v), 10) }
+
+func (v *uintValue) Set(s string) error {
+	n, err := strconv.ParseUint(s, 10, 0)
+	if err != nil {
+		return errors.New("must be a non-negative integer")
+	}
+	*v = uintValue(n)
+	return nil
+}
+
+type floatValue float64
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return errors.New("must be a number")
+	}
+	*v = floatValue(f)
+	return nil
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// clearEnv 清空所有配置项对应的环境变量，避免受运行环境影响 (Load 忽略空值)
+func clearEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("TINYLINK_CONFIG", "")
+	for _, s := range settings(Default()) {
+		t.Setenv(s.env, "")
+	}
+}
+
+// writeFile 在临时目录中写入配置文件并返回路径
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+	cfg, args, err := Load("test", []string{"extra"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Load without overrides = %+v, want the defaults", cfg)
+	}
+	if len(args) != 1 || args[0] != "extra" {
+		t.Errorf("remaining args = %v, want [extra]", args)
+	}
+}
+
+// TestLoadPrecedence 默认值 < 配置文件 < 环境变量 < 命令行参数
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "tinylink.yaml", `
+http:
+  addr: ":9000"
+  base_url: "https://file.example/"
+redis:
+  addr: "file-redis:6379"
+kafka:
+  topic: file_topic
+bloom:
+  capacity: 500
+`)
+	t.Setenv("TINYLINK_CONFIG", path)
+	t.Setenv("REDIS_HOST", "env-redis:6379")
+	t.Setenv("KAFKA_TOPIC", "env_topic")
+	t.Setenv("DOMAINS", "https://go.brand.test/, https://b.test")
+
+	cfg, _, err := Load("test", []string{"-kafka-topic", "flag_topic", "-dedup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"default", cfg.Database.Driver, "mysql"},
+		{"file", cfg.HTTP.Addr, ":9000"},
+		{"file, trailing slash trimmed", cfg.HTTP.BaseURL, "https://file.example"},
+		{"file", cfg.Bloom.Capacity, uint(500)},
+		{"env over file", cfg.Redis.Addr, "env-redis:6379"},
+		{"flag over env", cfg.Kafka.Topic, "flag_topic"},
+		{"bool flag without value", cfg.Shorten.Dedup, true},
+		{"list from env", strings.Join(cfg.HTTP.Domains, " "), "https://go.brand.test https://b.test"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "tinylink.toml", `
+mode = "standalone"
+
+[short_code]
+length = 8
+
+[quota]
+default_plan = "free"
+
+[quota.plans.free]
+monthly_links = 100
+`)
+	cfg, _, err := Load("test", []string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Mode != ModeStandalone || cfg.ShortCode.Length != 8 || cfg.Quota.Plans["free"].MonthlyLinks != 100 {
+		t.Errorf("Load(%s) = %+v", path, cfg)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name, file, content string
+		env                 map[string]string
+		want                string
+	}{
+		{name: "unknown YAML field", file: "c.yaml", content: "http:\n  adr: \":80\"\n", want: "parse config file"},
+		{name: "unknown TOML field", file: "c.toml", content: "[redis]\nhost = \"x\"\n", want: "parse config file"},
+		{name: "unsupported extension", file: "c.json", content: "{}", want: "unsupported config file"},
+		{name: "invalid env value", env: map[string]string{"REDIS_DB": "one"}, want: "invalid REDIS_DB"},
+		{name: "invalid bool env", env: map[string]string{"SHORTEN_DEDUP": "yes please"}, want: "must be true or false"},
+		{name: "validation failure", env: map[string]string{"TINYLINK_MODE": "cluster"}, want: "invalid configuration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			var args []string
+			if tt.file != "" {
+				args = []string{"-config", writeFile(t, tt.file, tt.content)}
+			}
+			if _, _, err := Load("test", args); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestExampleConfig 仓库中的示例配置与默认值一致且可以通过校验
+func TestExampleConfig(t *testing.T) {
+	clearEnv(t)
+	cfg, _, err := Load("test", []string{"-config", "../../config.example.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.HTTP.Addr != Default().HTTP.Addr || cfg.Bloom.Capacity != Default().Bloom.Capacity {
+		t.Errorf("config.example.yaml differs from the defaults: %+v", cfg)
+	}
+}
@@ -0,0 +1,127 @@
+// Package geoip 基于本地 MaxMind 格式 (MMDB) 数据库将 IP 解析为国家，支持文件更新后热加载
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// countryRecord GeoLite2-Country / GeoIP2-Country (以及 City) 数据库中用到的字段
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	// RegisteredCountry IP 段的注册国家，country 缺失时 (如部分 anycast 地址) 使用
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// DB 可热加载的 MMDB 数据库，并发安全
+type DB struct {
+	path string
+
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+	size    int64
+}
+
+// Open 打开 path 处的 MMDB 文件
+func Open(path string) (*DB, error) {
+	db := &DB{path: path}
+	if err := db.load(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// load 读取数据库文件并替换当前使用的 reader，旧 reader 在没有查询进行时关闭
+func (db *DB) load() error {
+	info, err := os.Stat(db.path)
+	if err != nil {
+		return err
+	}
+	reader, err := maxminddb.Open(db.path)
+	if err != nil {
+		return fmt.Errorf("open GeoIP database %s: %w", db.path, err)
+	}
+
+	db.mu.Lock()
+	old := db.reader
+	db.reader, db.modTime, db.size = reader, info.ModTime(), info.Size()
+	db.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	log.Printf("Loaded GeoIP database %s (%s, built %s)", db.path, reader.Metadata.DatabaseType,
+		time.Unix(int64(reader.Metadata.BuildEpoch), 0).UTC().Format(time.DateOnly))
+	return nil
+}
+
+// changed 判断数据库文件自上次加载后是否被修改
+func (db *DB) changed() bool {
+	info, err := os.Stat(db.path)
+	if err != nil {
+		return false // 更新过程中文件可能暂时不存在，继续使用已加载的版本
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return !info.ModTime().Equal(db.modTime) || info.Size() != db.size
+}
+
+// Watch 启动后台协程，每隔 interval 检查数据库文件的修改时间与大小，变化后重新加载
+// 加载失败时继续使用旧版本；ctx 取消后协程退出
+func (db *DB) Watch(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !db.changed() {
+					continue
+				}
+				if err := db.load(); err != nil {
+					log.Printf("Failed to reload GeoIP database, keeping the previous version: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Country 返回 IP 所属国家的 ISO 3166-1 代码 (大写)，无法解析时返回空字符串
+func (db *DB) Country(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+	var rec countryRecord
+	db.mu.RLock()
+	err := db.reader.Lookup(addr, &rec)
+	db.mu.RUnlock()
+	if err != nil {
+		return ""
+	}
+	if rec.Country.ISOCode != "" {
+		return rec.Country.ISOCode
+	}
+	return rec.RegisteredCountry.ISOCode
+}
+
+// Close 关闭数据库
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.reader.Close()
+}
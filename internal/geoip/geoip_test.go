@@ -0,0 +1,206 @@
+package geoip
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// mmdbRecord 搜索树中的一条记录：指向子节点 (node > 0) 或数据 (data >= 0)，都不是时表示未收录
+type mmdbRecord struct {
+	node, data int
+}
+
+// writeMMDB 以原子替换的方式写出只含国家字段的 IPv4 MMDB 文件，countries 为网段到国家代码的映射
+func writeMMDB(t *testing.T, path string, countries map[string]string) {
+	t.Helper()
+	empty := mmdbRecord{data: -1}
+	nodes := [][2]mmdbRecord{{empty, empty}}
+	var data bytes.Buffer
+	offsets := make(map[string]int)
+	for cidr, cc := range countries {
+		prefix := netip.MustParsePrefix(cidr)
+		if _, ok := offsets[cc]; !ok {
+			offsets[cc] = data.Len()
+			data.Write(mmdbMap(1))
+			data.Write(mmdbString("country"))
+			data.Write(mmdbMap(1))
+			data.Write(mmdbString("iso_code"))
+			data.Write(mmdbString(cc))
+		}
+		ip := binary.BigEndian.Uint32(prefix.Addr().AsSlice())
+		cur := 0
+		for i := range prefix.Bits() {
+			bit := ip >> (31 - i) & 1
+			if i == prefix.Bits()-1 {
+				nodes[cur][bit] = mmdbRecord{data: offsets[cc]}
+				break
+			}
+			if nodes[cur][bit].node == 0 {
+				nodes = append(nodes, [2]mmdbRecord{empty, empty})
+				nodes[cur][bit] = mmdbRecord{node: len(nodes) - 1, data: -1}
+			}
+			cur = nodes[cur][bit].node
+		}
+	}
+
+	var buf bytes.Buffer
+	count := len(nodes)
+	for _, n := range nodes {
+		for _, r := range n {
+			v := count // 未收录
+			switch {
+			case r.node > 0:
+				v = r.node
+			case r.data >= 0:
+				v = count + 16 + r.data
+			}
+			buf.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)})
+		}
+	}
+	buf.Write(make([]byte, 16))
+	buf.Write(data.Bytes())
+	buf.WriteString("\xab\xcd\xefMaxMind.com")
+	buf.Write(mmdbMap(9))
+	for _, kv := range []struct {
+		key   string
+		value []byte
+	}{
+		{"node_count", mmdbUint(6, uint64(count))},
+		{"record_size", mmdbUint(5, 24)},
+		{"ip_version", mmdbUint(5, 4)},
+		{"database_type", mmdbString("Test-Country")},
+		{"languages", append([]byte{1, 4}, mmdbString("en")...)},
+		{"binary_format_major_version", mmdbUint(5, 2)},
+		{"binary_format_minor_version", mmdbUint(5, 0)},
+		{"build_epoch", mmdbUint(9, 1700000000)},
+		{"description", append(append(mmdbMap(1), mmdbString("en")...), mmdbString("test")...)},
+	} {
+		buf.Write(mmdbString(kv.key))
+		buf.Write(kv.value)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mmdbString(s string) []byte {
+	return append([]byte{0x40 | byte(len(s))}, s...)
+}
+
+func mmdbMap(n int) []byte {
+	return []byte{0xe0 | byte(n)}
+}
+
+// mmdbUint 编码无符号整数，typ 为 5 (uint16)、6 (uint32) 或 9 (uint64，扩展类型)
+func mmdbUint(typ byte, v uint64) []byte {
+	var b []byte
+	for ; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	if typ <= 7 {
+		return append([]byte{typ<<5 | byte(len(b))}, b...)
+	}
+	return append([]byte{byte(len(b)), typ - 7}, b...)
+}
+
+func TestCountry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	writeMMDB(t, path, map[string]string{"81.2.69.0/24": "GB", "5.9.0.0/16": "DE"})
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for ip, want := range map[string]string{
+		"81.2.69.160": "GB",
+		"5.9.200.1":   "DE",
+		"10.0.0.1":    "",
+		"not an ip":   "",
+		"2001:db8::1": "",
+	} {
+		if got := db.Country(ip); got != want {
+			t.Errorf("Country(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Open(filepath.Join(dir, "missing.mmdb")); err == nil {
+		t.Error("Open of a missing file succeeded")
+	}
+	bad := filepath.Join(dir, "bad.mmdb")
+	if err := os.WriteFile(bad, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(bad); err == nil {
+		t.Error("Open of an invalid file succeeded")
+	}
+}
+
+// waitCountry 等待 Watch 重新加载后 ip 解析为 want
+func waitCountry(t *testing.T, db *DB, ip, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for db.Country(ip) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Country(%q) = %q, want %q after reload", ip, db.Country(ip), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestWatch 数据库文件更新后自动重新加载，文件损坏或被删除时继续使用已加载的版本
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	writeMMDB(t, path, map[string]string{"81.2.69.0/24": "GB"})
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db.Watch(ctx, 5*time.Millisecond)
+
+	writeMMDB(t, path, map[string]string{"81.2.69.0/24": "FR", "5.9.0.0/16": "DE"})
+	waitCountry(t, db, "81.2.69.160", "FR")
+	if got := db.Country("5.9.0.1"); got != "DE" {
+		t.Errorf("Country(5.9.0.1) = %q after reload, want DE", got)
+	}
+
+	if err := os.WriteFile(path+".tmp", []byte("truncated download"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := db.Country("81.2.69.160"); got != "FR" {
+		t.Errorf("Country = %q after an invalid update, want the previous FR", got)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := db.Country("81.2.69.160"); got != "FR" {
+		t.Errorf("Country = %q after the file was removed, want the previous FR", got)
+	}
+
+	// 修复后的文件再次被加载
+	writeMMDB(t, path, map[string]string{"81.2.69.0/24": "IE"})
+	waitCountry(t, db, "81.2.69.160", "IE")
+}
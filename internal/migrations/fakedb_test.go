@@ -0,0 +1,365 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeDB 只记录表结构的内存数据库，用于在没有 MySQL / PostgreSQL 的环境中验证迁移脚本
+// 能解析迁移脚本中用到的 DDL 语句，以真实数据库的方式拒绝重复创建、删除不存在的对象等操作
+type fakeDB struct {
+	driver string
+
+	mu        sync.Mutex
+	tables    map[string]*fakeTable
+	sequences map[string]bool
+	records   map[int64][]driver.Value // schema_migrations 的行：version -> (version, checksum, applied_at)
+	execs     []string                 // 执行过的语句
+}
+
+type fakeTable struct {
+	columns []string
+	indexes map[string][]string // 索引 / 约束名 -> 列
+}
+
+// openFakeDB 创建空的 fakeDB 并返回连接到它的 *sql.DB
+func openFakeDB(driverName string) (*sql.DB, *fakeDB) {
+	f := &fakeDB{
+		driver:    driverName,
+		tables:    make(map[string]*fakeTable),
+		sequences: make(map[string]bool),
+		records:   make(map[int64][]driver.Value),
+	}
+	db := sql.OpenDB(fakeConnector{f})
+	db.SetMaxOpenConns(1)
+	return db, f
+}
+
+// columns 返回表的列名，表不存在时返回 nil
+func (f *fakeDB) columns(table string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t, ok := f.tables[table]; ok {
+		return slices.Clone(t.columns)
+	}
+	return nil
+}
+
+func (f *fakeDB) hasTable(table string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.tables[table]
+	return ok
+}
+
+// executed 返回执行过的语句
+func (f *fakeDB) executed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.execs)
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, fmt.Errorf("use fakeConnector") }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.execs = append(c.db.execs, query)
+	return driver.RowsAffected(0), c.db.exec(normalize(query), args)
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	return c.db.query(normalize(query), args)
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+var spaces = regexp.MustCompile(`\s+`)
+
+func normalize(query string) string {
+	return strings.TrimSpace(spaces.ReplaceAllString(query, " "))
+}
+
+var (
+	reCreateTable    = regexp.MustCompile(`^CREATE TABLE (IF NOT EXISTS )?(\w+) \((.*)\)[^)]*$`)
+	reDropTable      = regexp.MustCompile(`^DROP TABLE (IF EXISTS )?(\w+)$`)
+	reCreateSequence = regexp.MustCompile(`^CREATE SEQUENCE (IF NOT EXISTS )?(\w+)$`)
+	reDropSequence   = regexp.MustCompile(`^DROP SEQUENCE (IF EXISTS )?(\w+)$`)
+	reCreateIndex    = regexp.MustCompile(`^CREATE (?:UNIQUE )?INDEX (IF NOT EXISTS )?(\w+) ON (\w+) \((.*)\)$`)
+	reDropIndex      = regexp.MustCompile(`^DROP INDEX (IF EXISTS )?(\w+)$`)
+	reAlterTable     = regexp.MustCompile(`^ALTER TABLE (\w+) (.*)$`)
+	reIndexDef       = regexp.MustCompile(`^(?:UNIQUE KEY|KEY|CONSTRAINT) (\w+) (?:UNIQUE |PRIMARY KEY )?\((.*)\)$`)
+	rePrimaryKey     = regexp.MustCompile(`^PRIMARY KEY \(.*\)$`)
+)
+
+// exec 执行一条 DDL 或 schema_migrations 上的写操作，调用方持有锁
+func (f *fakeDB) exec(q string, args []driver.NamedValue) error {
+	if m := reCreateTable.FindStringSubmatch(q); m != nil {
+		if _, ok := f.tables[m[2]]; ok {
+			if m[1] != "" {
+				return nil
+			}
+			return fmt.Errorf("table %q already exists", m[2])
+		}
+		t := &fakeTable{indexes: make(map[string][]string)}
+		for _, def := range splitTopLevel(m[3]) {
+			switch {
+			case rePrimaryKey.MatchString(def):
+			case reIndexDef.MatchString(def):
+				idx := reIndexDef.FindStringSubmatch(def)
+				t.indexes[idx[1]] = splitTopLevel(idx[2])
+			default:
+				t.columns = append(t.columns, strings.Fields(def)[0])
+			}
+		}
+		for name, cols := range t.indexes {
+			if err := t.checkColumns(name, cols); err != nil {
+				return err
+			}
+		}
+		f.tables[m[2]] = t
+		return nil
+	}
+	if m := reDropTable.FindStringSubmatch(q); m != nil {
+		if _, ok := f.tables[m[2]]; !ok && m[1] == "" {
+			return fmt.Errorf("table %q does not exist", m[2])
+		}
+		delete(f.tables, m[2])
+		return nil
+	}
+	if m := reCreateSequence.FindStringSubmatch(q); m != nil {
+		if f.sequences[m[2]] && m[1] == "" {
+			return fmt.Errorf("sequence %q already exists", m[2])
+		}
+		f.sequences[m[2]] = true
+		return nil
+	}
+	if m := reDropSequence.FindStringSubmatch(q); m != nil {
+		if !f.sequences[m[2]] && m[1] == "" {
+			return fmt.Errorf("sequence %q does not exist", m[2])
+		}
+		delete(f.sequences, m[2])
+		return nil
+	}
+	if m := reCreateIndex.FindStringSubmatch(q); m != nil {
+		t, ok := f.tables[m[3]]
+		if !ok {
+			return fmt.Errorf("table %q does not exist", m[3])
+		}
+		if f.indexTable(m[2]) != nil {
+			if m[1] != "" {
+				return nil
+			}
+			return fmt.Errorf("index %q already exists", m[2])
+		}
+		return t.addIndex(m[2], splitTopLevel(m[4]))
+	}
+	if m := reDropIndex.FindStringSubmatch(q); m != nil {
+		t := f.indexTable(m[2])
+		if t == nil {
+			if m[1] != "" {
+				return nil
+			}
+			return fmt.Errorf("index %q does not exist", m[2])
+		}
+		delete(t.indexes, m[2])
+		return nil
+	}
+	if m := reAlterTable.FindStringSubmatch(q); m != nil {
+		t, ok := f.tables[m[1]]
+		if !ok {
+			return fmt.Errorf("table %q does not exist", m[1])
+		}
+		for _, action := range splitTopLevel(m[2]) {
+			if err := f.alter(t, action); err != nil {
+				return fmt.Errorf("ALTER TABLE %s: %w", m[1], err)
+			}
+		}
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(q, "INSERT INTO schema_migrations "):
+		if _, ok := f.tables["schema_migrations"]; !ok {
+			return fmt.Errorf("table %q does not exist", "schema_migrations")
+		}
+		version := args[0].Value.(int64)
+		if _, ok := f.records[version]; ok {
+			return fmt.Errorf("duplicate schema_migrations version %d", version)
+		}
+		f.records[version] = []driver.Value{version, args[2].Value, time.Now()}
+		return nil
+	case strings.HasPrefix(q, "DELETE FROM schema_migrations "):
+		delete(f.records, args[0].Value.(int64))
+		return nil
+	}
+	return fmt.Errorf("fakedb: unsupported statement %q", q)
+}
+
+// alter 执行 ALTER TABLE 中的一个子句
+func (f *fakeDB) alter(t *fakeTable, action string) error {
+	fields := strings.Fields(action)
+	switch {
+	case strings.HasPrefix(action, "ADD COLUMN "):
+		if slices.Contains(t.columns, fields[2]) {
+			return fmt.Errorf("duplicate column %q", fields[2])
+		}
+		if i := slices.Index(fields, "AFTER"); i > 0 && !slices.Contains(t.columns, fields[i+1]) {
+			return fmt.Errorf("unknown column %q in AFTER", fields[i+1])
+		}
+		t.columns = append(t.columns, fields[2])
+	case strings.HasPrefix(action, "DROP COLUMN "):
+		i := slices.Index(t.columns, fields[2])
+		if i < 0 {
+			return fmt.Errorf("column %q does not exist", fields[2])
+		}
+		t.columns = slices.Delete(t.columns, i, i+1)
+		for name, cols := range t.indexes {
+			if !slices.Contains(cols, fields[2]) {
+				continue
+			}
+			// MySQL 从索引中移除该列，PostgreSQL 删除整个索引
+			cols = slices.DeleteFunc(cols, func(c string) bool { return c == fields[2] })
+			if f.driver == "postgres" || len(cols) == 0 {
+				delete(t.indexes, name)
+			} else {
+				t.indexes[name] = cols
+			}
+		}
+	case strings.HasPrefix(action, "ADD "):
+		m := reIndexDef.FindStringSubmatch(strings.TrimPrefix(action, "ADD "))
+		if m == nil {
+			return fmt.Errorf("fakedb: unsupported clause %q", action)
+		}
+		if f.indexTable(m[1]) != nil {
+			return fmt.Errorf("duplicate key name %q", m[1])
+		}
+		return t.addIndex(m[1], splitTopLevel(m[2]))
+	case strings.HasPrefix(action, "DROP INDEX "), strings.HasPrefix(action, "DROP CONSTRAINT "):
+		if _, ok := t.indexes[fields[2]]; !ok {
+			return fmt.Errorf("can't drop %q; check that it exists", fields[2])
+		}
+		delete(t.indexes, fields[2])
+	default:
+		return fmt.Errorf("fakedb: unsupported clause %q", action)
+	}
+	return nil
+}
+
+func (t *fakeTable) addIndex(name string, cols []string) error {
+	if err := t.checkColumns(name, cols); err != nil {
+		return err
+	}
+	t.indexes[name] = cols
+	return nil
+}
+
+func (t *fakeTable) checkColumns(index string, cols []string) error {
+	for _, c := range cols {
+		if !slices.Contains(t.columns, c) {
+			return fmt.Errorf("index %q: column %q does not exist", index, c)
+		}
+	}
+	return nil
+}
+
+// indexTable 返回包含该索引的表，不存在时返回 nil
+func (f *fakeDB) indexTable(name string) *fakeTable {
+	for _, t := range f.tables {
+		if _, ok := t.indexes[name]; ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// query 执行 schema_migrations 上的查询，调用方持有锁
+func (f *fakeDB) query(q string, args []driver.NamedValue) (driver.Rows, error) {
+	if strings.HasPrefix(q, "SELECT COUNT(*) FROM information_schema.tables ") {
+		var n int64
+		if _, ok := f.tables[args[0].Value.(string)]; ok {
+			n = 1
+		}
+		return &fakeRows{columns: []string{"count"}, values: [][]driver.Value{{n}}}, nil
+	}
+	if q == "SELECT version, checksum, applied_at FROM schema_migrations" {
+		if _, ok := f.tables["schema_migrations"]; !ok {
+			return nil, fmt.Errorf("table %q does not exist", "schema_migrations")
+		}
+		rows := &fakeRows{columns: []string{"version", "checksum", "applied_at"}}
+		for _, version := range slices.Sorted(maps.Keys(f.records)) {
+			rows.values = append(rows.values, f.records[version])
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("fakedb: unsupported query %q", q)
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// splitTopLevel 按不在括号内的逗号拆分，并去掉各部分两端的空白
+func splitTopLevel(s string) []string {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
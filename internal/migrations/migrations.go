@@ -0,0 +1,319 @@
+// Package migrations 管理数据库结构的版本化迁移
+//
+// 迁移脚本按数据库类型存放在 mysql/ 与 postgres/ 目录下，文件名格式为
+// NNNN_name.up.sql / NNNN_name.down.sql，按版本号顺序执行。
+// 已执行的迁移及其脚本校验和记录在 schema_migrations 表中。
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yin1895/tinylink/internal/sqlbind"
+)
+
+//go:embed mysql/*.sql postgres/*.sql
+var files embed.FS
+
+var (
+	// ErrSchemaBehind 表示存在尚未执行的迁移
+	ErrSchemaBehind = errors.New("database schema is behind, run `tinylink migrate up`")
+	// ErrChecksumMismatch 表示已执行的迁移脚本在之后被修改过
+	ErrChecksumMismatch = errors.New("applied migration has been modified")
+)
+
+// Migration 一个版本的迁移脚本
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // Up 脚本的 SHA-256
+}
+
+// Status 单个迁移的执行状态
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool // 已执行的脚本与记录的校验和不一致
+}
+
+// Migrator 在指定数据库上执行迁移
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []Migration
+}
+
+// New 创建迁移器，driver 为 mysql 或 postgres
+func New(db *sql.DB, driver string) (*Migrator, error) {
+	migrations, err := load(driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, driver: driver, migrations: migrations}, nil
+}
+
+// Check 校验数据库结构是否为最新，服务启动时调用
+// 只读取 schema_migrations，不会创建表；表不存在时视为尚未执行任何迁移
+func Check(ctx context.Context, db *sql.DB, driver string) error {
+	m, err := New(db, driver)
+	if err != nil {
+		return err
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, st := range statuses {
+		if st.Modified {
+			return fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, st.Version, st.Name)
+		}
+		if !st.Applied {
+			return fmt.Errorf("%w (pending %04d_%s)", ErrSchemaBehind, st.Version, st.Name)
+		}
+	}
+	return nil
+}
+
+// load 读取并按版本号排序内嵌的迁移脚本
+func load(driver string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, driver)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for driver %q", driver)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var (
+			base string
+			up   bool
+		)
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			base, up = strings.TrimSuffix(name, ".up.sql"), true
+		case strings.HasSuffix(name, ".down.sql"):
+			base = strings.TrimSuffix(name, ".down.sql")
+		default:
+			continue
+		}
+		versionStr, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+		content, err := files.ReadFile(path.Join(driver, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		}
+		if up {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down scripts", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Status 返回所有迁移的执行状态，只读取数据库，不会创建 schema_migrations 表
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, len(m.migrations))
+	for i, mig := range m.migrations {
+		statuses[i] = Status{Migration: mig}
+		if rec, ok := applied[mig.Version]; ok {
+			statuses[i].Applied = true
+			statuses[i].AppliedAt = rec.appliedAt
+			statuses[i].Modified = rec.checksum != mig.Checksum
+		}
+	}
+	return statuses, nil
+}
+
+// Up 依次执行所有未执行的迁移，返回本次执行的迁移，必要时创建 schema_migrations 表
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, st := range statuses {
+		if st.Modified {
+			return done, fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, st.Version, st.Name)
+		}
+		if st.Applied {
+			continue
+		}
+		if err := m.run(ctx, st.Up, m.bind("INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)"),
+			st.Version, st.Name, st.Checksum); err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", st.Version, st.Name, err)
+		}
+		done = append(done, st.Migration)
+	}
+	return done, nil
+}
+
+// Down 按版本号倒序回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(statuses) - 1; i >= 0 && len(done) < steps; i-- {
+		st := statuses[i]
+		if !st.Applied {
+			continue
+		}
+		if err := m.run(ctx, st.Down, m.bind("DELETE FROM schema_migrations WHERE version = ?"), st.Version); err != nil {
+			return done, fmt.Errorf("rollback %04d_%s: %w", st.Version, st.Name, err)
+		}
+		done = append(done, st.Migration)
+	}
+	return done, nil
+}
+
+// run 执行迁移脚本并更新 schema_migrations
+// PostgreSQL 支持事务性 DDL，失败时整体回滚；MySQL 的 DDL 会隐式提交，失败时需人工处理
+func (m *Migrator) run(ctx context.Context, script, record string, args ...any) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range splitStatements(script) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type appliedRecord struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// applied 读取已执行的迁移记录，schema_migrations 表不存在时返回空记录
+func (m *Migrator) applied(ctx context.Context) (map[int64]appliedRecord, error) {
+	exists, err := m.tableExists(ctx)
+	if err != nil || !exists {
+		return map[int64]appliedRecord{}, err
+	}
+	rows, err := m.db.QueryContext(ctx, "SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedRecord)
+	for rows.Next() {
+		var (
+			version int64
+			rec     appliedRecord
+		)
+		if err := rows.Scan(&version, &rec.checksum, &rec.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = rec
+	}
+	return applied, rows.Err()
+}
+
+// tableExists 判断当前库 (PostgreSQL 为当前 schema) 中是否已有 schema_migrations 表
+func (m *Migrator) tableExists(ctx context.Context) (bool, error) {
+	current := "DATABASE()"
+	if m.driver == "postgres" {
+		current = "current_schema()"
+	}
+	var n int
+	err := m.db.QueryRowContext(ctx, m.bind(
+		"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = "+current+" AND table_name = ?"),
+		"schema_migrations").Scan(&n)
+	return n > 0, err
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	appliedAt := "DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP"
+	if m.driver == "postgres" {
+		appliedAt = "TIMESTAMPTZ NOT NULL DEFAULT NOW()"
+	}
+	_, err := m.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		applied_at `+appliedAt+`
+	)`)
+	return err
+}
+
+// bind 将 ? 占位符转换为 PostgreSQL 的 $n 格式
+func (m *Migrator) bind(query string) string {
+	if m.driver != "postgres" {
+		return query
+	}
+	return sqlbind.Dollar(query)
+}
+
+// splitStatements 按分号拆分脚本中的多条语句，忽略 -- 注释行
+// 迁移脚本中不应在字符串字面量里使用分号
+func splitStatements(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	var stmts []string
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
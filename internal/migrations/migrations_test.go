@@ -0,0 +1,227 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// baseline 引入迁移之前程序启动时自动创建的表结构
+var baseline = map[string][]string{
+	"mysql": {
+		`CREATE TABLE IF NOT EXISTS urls (
+		id BIGINT NOT NULL,
+		long_url VARCHAR(2048) NOT NULL,
+		PRIMARY KEY (id)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+		`CREATE TABLE IF NOT EXISTS tickets (
+		id BIGINT NOT NULL AUTO_INCREMENT,
+		stub CHAR(1) NOT NULL DEFAULT 'a',
+		PRIMARY KEY (id)
+	) ENGINE=InnoDB;`,
+	},
+	"postgres": {
+		`CREATE TABLE IF NOT EXISTS urls (id BIGINT NOT NULL PRIMARY KEY, long_url VARCHAR(2048) NOT NULL)`,
+		`CREATE SEQUENCE IF NOT EXISTS tickets_id_seq`,
+	},
+}
+
+// urlColumns 迁移到最新版本后 urls 表应有的列 (与 storage 中的 linkColumns 对应)
+var urlColumns = []string{
+	"id", "long_url", "targets", "geo_targets", "variants", "query_params", "pass_query", "domain", "owner_id",
+	"alias", "url_hash", "password_hash", "max_clicks", "clicks", "not_before", "not_after", "expires_at", "created_at",
+}
+
+var drivers = []string{"mysql", "postgres"}
+
+func newFakeMigrator(t *testing.T, driver string) (*Migrator, *fakeDB) {
+	t.Helper()
+	db, f := openFakeDB(driver)
+	t.Cleanup(func() { db.Close() })
+	m, err := New(db, driver)
+	if err != nil {
+		t.Fatalf("New(%s): %v", driver, err)
+	}
+	return m, f
+}
+
+func assertLatest(t *testing.T, driver string, m *Migrator, f *fakeDB) {
+	t.Helper()
+	if got, want := slices.Sorted(slices.Values(f.columns("urls"))), slices.Sorted(slices.Values(urlColumns)); !slices.Equal(got, want) {
+		t.Errorf("%s: urls columns = %v, want %v", driver, got, want)
+	}
+	for _, table := range []string{"id_segments", "api_keys", "link_usage"} {
+		if !f.hasTable(table) {
+			t.Errorf("%s: table %s missing", driver, table)
+		}
+	}
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("%s: Status: %v", driver, err)
+	}
+	for _, st := range statuses {
+		if !st.Applied || st.Modified {
+			t.Errorf("%s: %04d_%s applied=%v modified=%v", driver, st.Version, st.Name, st.Applied, st.Modified)
+		}
+	}
+}
+
+// TestUpFromBaseline 在引入迁移之前的数据库上执行所有迁移
+func TestUpFromBaseline(t *testing.T) {
+	ctx := context.Background()
+	for _, driver := range drivers {
+		m, f := newFakeMigrator(t, driver)
+		for _, stmt := range baseline[driver] {
+			if _, err := m.db.ExecContext(ctx, stmt); err != nil {
+				t.Fatalf("%s: baseline: %v", driver, err)
+			}
+		}
+		done, err := m.Up(ctx)
+		if err != nil {
+			t.Fatalf("%s: Up on baseline schema: %v", driver, err)
+		}
+		if len(done) != len(m.migrations) {
+			t.Errorf("%s: applied %d migrations, want %d", driver, len(done), len(m.migrations))
+		}
+		assertLatest(t, driver, m, f)
+
+		again, err := m.Up(ctx)
+		if err != nil || len(again) != 0 {
+			t.Errorf("%s: second Up = %d migrations, %v; want none", driver, len(again), err)
+		}
+	}
+}
+
+// TestInitMatchesBaseline 第一个迁移只包含引入迁移之前已有的表，
+// 之后新增的列、索引和表都应由后续迁移以 ALTER / CREATE 的方式加入
+func TestInitMatchesBaseline(t *testing.T) {
+	ctx := context.Background()
+	for _, driver := range drivers {
+		m, f := newFakeMigrator(t, driver)
+		m.migrations = m.migrations[:1]
+		if _, err := m.Up(ctx); err != nil {
+			t.Fatalf("%s: Up: %v", driver, err)
+		}
+		if got := f.columns("urls"); !slices.Equal(got, []string{"id", "long_url"}) {
+			t.Errorf("%s: 0001 creates urls columns %v, want [id long_url]", driver, got)
+		}
+		if f.hasTable("id_segments") {
+			t.Errorf("%s: 0001 creates id_segments", driver)
+		}
+	}
+}
+
+// TestDownAndUp 从空数据库迁移到最新版本，全部回滚后再次迁移
+func TestDownAndUp(t *testing.T) {
+	ctx := context.Background()
+	for _, driver := range drivers {
+		m, f := newFakeMigrator(t, driver)
+		if _, err := m.Up(ctx); err != nil {
+			t.Fatalf("%s: Up on empty database: %v", driver, err)
+		}
+		assertLatest(t, driver, m, f)
+
+		done, err := m.Down(ctx, len(m.migrations))
+		if err != nil {
+			t.Fatalf("%s: Down: %v", driver, err)
+		}
+		if len(done) != len(m.migrations) {
+			t.Errorf("%s: rolled back %d migrations, want %d", driver, len(done), len(m.migrations))
+		}
+		if f.hasTable("urls") {
+			t.Errorf("%s: urls still exists after rolling back everything", driver)
+		}
+
+		if _, err := m.Up(ctx); err != nil {
+			t.Fatalf("%s: Up after Down: %v", driver, err)
+		}
+		assertLatest(t, driver, m, f)
+	}
+}
+
+// TestCheck 服务启动时的检查：未迁移时报告版本落后，且不修改数据库
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	for _, driver := range drivers {
+		m, f := newFakeMigrator(t, driver)
+		if err := Check(ctx, m.db, driver); !errors.Is(err, ErrSchemaBehind) {
+			t.Errorf("%s: Check before migrating = %v, want ErrSchemaBehind", driver, err)
+		}
+		if statuses, err := m.Status(ctx); err != nil || len(statuses) != len(m.migrations) {
+			t.Errorf("%s: Status before migrating = %d statuses, %v", driver, len(statuses), err)
+		}
+		// Check 与 Status 只读，不应创建 schema_migrations 表
+		if execs := f.executed(); len(execs) != 0 {
+			t.Errorf("%s: Check executed %q, want no writes", driver, execs)
+		}
+		if _, err := m.Up(ctx); err != nil {
+			t.Fatalf("%s: Up: %v", driver, err)
+		}
+		if err := Check(ctx, m.db, driver); err != nil {
+			t.Errorf("%s: Check after migrating = %v", driver, err)
+		}
+	}
+}
+
+func TestLoadBothDrivers(t *testing.T) {
+	mysql, err := load("mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	postgres, err := load("postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mysql) != len(postgres) {
+		t.Fatalf("mysql has %d migrations, postgres has %d", len(mysql), len(postgres))
+	}
+	for i := range mysql {
+		if mysql[i].Version != int64(i+1) {
+			t.Errorf("mysql migration %d has version %d, want consecutive versions", i, mysql[i].Version)
+		}
+		if mysql[i].Version != postgres[i].Version || mysql[i].Name != postgres[i].Name {
+			t.Errorf("migration %d: mysql %04d_%s, postgres %04d_%s",
+				i, mysql[i].Version, mysql[i].Name, postgres[i].Version, postgres[i].Name)
+		}
+	}
+	if _, err := load("sqlite"); err == nil {
+		t.Error("load(sqlite) succeeded, want error")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		script string
+		want   []string
+	}{
+		{"", nil},
+		{"-- only a comment\n", nil},
+		{"SELECT 1;", []string{"SELECT 1"}},
+		{"-- comment\nSELECT 1;\n\n  -- another\nSELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{"ALTER TABLE t\n\tADD COLUMN a INT;\n;\n", []string{"ALTER TABLE t\n\tADD COLUMN a INT"}},
+	}
+	for _, tt := range tests {
+		if got := splitStatements(tt.script); !slices.Equal(got, tt.want) {
+			t.Errorf("splitStatements(%q) = %q, want %q", tt.script, got, tt.want)
+		}
+	}
+}
+
+// TestBaselineWithoutMigrationsFails 确认 fakeDB 能发现旧版 0001 的问题：
+// 已有的 urls 表使 CREATE TABLE IF NOT EXISTS 不生效，后续删除不存在的索引时失败
+func TestBaselineWithoutMigrationsFails(t *testing.T) {
+	db, _ := openFakeDB("mysql")
+	defer db.Close()
+	ctx := context.Background()
+	for _, stmt := range baseline["mysql"] {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := db.ExecContext(ctx, "ALTER TABLE urls DROP INDEX uk_alias")
+	if err == nil || !strings.Contains(err.Error(), "uk_alias") {
+		t.Errorf("dropping a missing index = %v, want error", err)
+	}
+}
@@ -0,0 +1,16 @@
+-- 初始结构：与引入迁移之前程序自动创建的表一致
+-- 使用 IF NOT EXISTS，已有数据库执行 migrate up 即可纳入版本管理
+
+-- 短链接表
+CREATE TABLE IF NOT EXISTS urls (
+	id BIGINT NOT NULL,
+	long_url VARCHAR(2048) NOT NULL,
+	PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 旧的逐条发号表
+CREATE TABLE IF NOT EXISTS tickets (
+	id BIGINT NOT NULL AUTO_INCREMENT,
+	stub CHAR(1) NOT NULL DEFAULT 'a',
+	PRIMARY KEY (id)
+) ENGINE=InnoDB;
@@ -0,0 +1,3 @@
+ALTER TABLE urls
+	DROP INDEX uk_alias,
+	DROP COLUMN alias;
@@ -0,0 +1,4 @@
+-- 自定义别名：按字节比较以保持大小写敏感，NULL 表示使用 ID 编码的短码
+ALTER TABLE urls
+	ADD COLUMN alias VARCHAR(64) CHARACTER SET ascii COLLATE ascii_bin NULL AFTER long_url,
+	ADD UNIQUE KEY uk_alias (alias);
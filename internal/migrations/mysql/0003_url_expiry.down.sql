@@ -0,0 +1,4 @@
+ALTER TABLE urls
+	DROP INDEX idx_expires_at,
+	DROP COLUMN created_at,
+	DROP COLUMN expires_at;
@@ -0,0 +1,5 @@
+-- 过期时间：NULL 表示永不过期，过期的链接由后台任务定期清理；已有链接的创建时间记为执行迁移的时间
+ALTER TABLE urls
+	ADD COLUMN expires_at DATETIME NULL AFTER alias,
+	ADD COLUMN created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP AFTER expires_at,
+	ADD KEY idx_expires_at (expires_at);
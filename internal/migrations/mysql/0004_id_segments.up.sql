@@ -0,0 +1,7 @@
+-- 号段表 (Leaf-segment 模式，一次数据库操作预留一段 ID)
+CREATE TABLE id_segments (
+	biz_tag VARCHAR(64) NOT NULL,
+	max_id BIGINT NOT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	PRIMARY KEY (biz_tag)
+) ENGINE=InnoDB;
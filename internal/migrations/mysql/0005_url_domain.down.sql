@@ -0,0 +1,5 @@
+-- 各域名之间存在同名别名时回滚会失败，需先手动处理
+ALTER TABLE urls
+	DROP INDEX uk_alias,
+	ADD UNIQUE KEY uk_alias (alias),
+	DROP COLUMN domain;
@@ -0,0 +1,5 @@
+-- 多域名：链接归属于某个域名 (空字符串表示默认域名)，别名在域名内唯一
+ALTER TABLE urls
+	ADD COLUMN domain VARCHAR(255) CHARACTER SET ascii COLLATE ascii_bin NOT NULL DEFAULT '' AFTER long_url,
+	DROP INDEX uk_alias,
+	ADD UNIQUE KEY uk_alias (domain, alias);
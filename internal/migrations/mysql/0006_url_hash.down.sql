@@ -0,0 +1,3 @@
+ALTER TABLE urls
+	DROP INDEX uk_url_hash,
+	DROP COLUMN url_hash;
@@ -0,0 +1,4 @@
+-- 去重模式：长链接规范化后的 SHA-256，在域名内唯一 (NULL 表示不参与去重)
+ALTER TABLE urls
+	ADD COLUMN url_hash CHAR(64) CHARACTER SET ascii COLLATE ascii_bin NULL AFTER alias,
+	ADD UNIQUE KEY uk_url_hash (domain, url_hash);
@@ -0,0 +1,8 @@
+-- 不同用户对同一长链接的去重记录会导致回滚失败，需先手动处理
+ALTER TABLE urls
+	DROP INDEX idx_owner,
+	DROP INDEX uk_url_hash,
+	ADD UNIQUE KEY uk_url_hash (domain, url_hash),
+	DROP COLUMN owner_id;
+
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,18 @@
+-- API Key 认证：只保存 key 的 SHA-256，明文仅在创建时展示一次
+CREATE TABLE api_keys (
+	id BIGINT NOT NULL AUTO_INCREMENT,
+	owner_id VARCHAR(64) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+	name VARCHAR(255) NOT NULL DEFAULT '',
+	key_hash CHAR(64) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	revoked_at DATETIME NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY uk_key_hash (key_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 链接归属的用户 (空字符串表示未启用认证时创建)，去重改为在域名 + 用户内进行
+ALTER TABLE urls
+	ADD COLUMN owner_id VARCHAR(64) CHARACTER SET ascii COLLATE ascii_bin NOT NULL DEFAULT '' AFTER domain,
+	DROP INDEX uk_url_hash,
+	ADD UNIQUE KEY uk_url_hash (domain, owner_id, url_hash),
+	ADD KEY idx_owner (owner_id, id);
@@ -0,0 +1,2 @@
+-- 密码保护：bcrypt 哈希，NULL 表示无需密码
+ALTER TABLE urls ADD COLUMN password_hash VARCHAR(60) CHARACTER SET ascii COLLATE ascii_bin NULL AFTER url_hash;
@@ -0,0 +1,4 @@
+-- 限次链接：max_clicks 为允许的访问次数 (0 表示不限)，clicks 为已计入的次数
+ALTER TABLE urls
+	ADD COLUMN max_clicks BIGINT NOT NULL DEFAULT 0 AFTER password_hash,
+	ADD COLUMN clicks BIGINT NOT NULL DEFAULT 0 AFTER max_clicks;
@@ -0,0 +1,3 @@
+ALTER TABLE urls
+	DROP COLUMN not_after,
+	DROP COLUMN not_before;
@@ -0,0 +1,2 @@
+-- A/B 分流：[{"name","url","weight"}] 的 JSON 数组，NULL 表示不分流
+ALTER TABLE urls ADD COLUMN variants TEXT NULL AFTER geo_targets;
@@ -0,0 +1,3 @@
+ALTER TABLE urls
+	DROP COLUMN pass_query,
+	DROP COLUMN query_params;
@@ -0,0 +1,2 @@
+DROP SEQUENCE IF EXISTS tickets_id_seq;
+DROP TABLE IF EXISTS urls;
@@ -0,0 +1,11 @@
+-- 初始结构：与引入迁移之前程序自动创建的表一致
+-- 使用 IF NOT EXISTS，已有数据库执行 migrate up 即可纳入版本管理
+
+-- 短链接表 (与 MySQL 结构一致)
+CREATE TABLE IF NOT EXISTS urls (
+	id BIGINT NOT NULL PRIMARY KEY,
+	long_url VARCHAR(2048) NOT NULL
+);
+
+-- 用序列代替 MySQL 的 tickets 自增表
+CREATE SEQUENCE IF NOT EXISTS tickets_id_seq;
@@ -0,0 +1,2 @@
+ALTER TABLE urls DROP CONSTRAINT uk_alias;
+ALTER TABLE urls DROP COLUMN alias;
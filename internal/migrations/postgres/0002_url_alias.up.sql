@@ -0,0 +1,3 @@
+-- 自定义别名：按字节比较以保持大小写敏感，NULL 表示使用 ID 编码的短码
+ALTER TABLE urls ADD COLUMN alias VARCHAR(64) COLLATE "C" NULL;
+ALTER TABLE urls ADD CONSTRAINT uk_alias UNIQUE (alias);
@@ -0,0 +1,4 @@
+-- 过期时间：NULL 表示永不过期，过期的链接由后台任务定期清理；已有链接的创建时间记为执行迁移的时间
+ALTER TABLE urls ADD COLUMN expires_at TIMESTAMPTZ NULL;
+ALTER TABLE urls ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+CREATE INDEX idx_expires_at ON urls (expires_at);
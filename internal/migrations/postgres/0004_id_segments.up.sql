@@ -0,0 +1,6 @@
+-- 号段表 (Leaf-segment 模式，一次数据库操作预留一段 ID)
+CREATE TABLE id_segments (
+	biz_tag VARCHAR(64) NOT NULL PRIMARY KEY,
+	max_id BIGINT NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+-- 各域名之间存在同名别名时回滚会失败，需先手动处理
+ALTER TABLE urls DROP CONSTRAINT uk_alias;
+ALTER TABLE urls ADD CONSTRAINT uk_alias UNIQUE (alias);
+ALTER TABLE urls DROP COLUMN domain;
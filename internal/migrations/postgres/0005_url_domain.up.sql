@@ -0,0 +1,4 @@
+-- 多域名：链接归属于某个域名 (空字符串表示默认域名)，别名在域名内唯一
+ALTER TABLE urls ADD COLUMN domain VARCHAR(255) COLLATE "C" NOT NULL DEFAULT '';
+ALTER TABLE urls DROP CONSTRAINT uk_alias;
+ALTER TABLE urls ADD CONSTRAINT uk_alias UNIQUE (domain, alias);
@@ -0,0 +1,2 @@
+ALTER TABLE urls DROP CONSTRAINT uk_url_hash;
+ALTER TABLE urls DROP COLUMN url_hash;
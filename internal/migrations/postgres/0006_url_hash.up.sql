@@ -0,0 +1,3 @@
+-- 去重模式：长链接规范化后的 SHA-256，在域名内唯一 (NULL 表示不参与去重)
+ALTER TABLE urls ADD COLUMN url_hash CHAR(64) NULL;
+ALTER TABLE urls ADD CONSTRAINT uk_url_hash UNIQUE (domain, url_hash);
@@ -0,0 +1,7 @@
+-- 不同用户对同一长链接的去重记录会导致回滚失败，需先手动处理
+DROP INDEX idx_owner;
+ALTER TABLE urls DROP CONSTRAINT uk_url_hash;
+ALTER TABLE urls ADD CONSTRAINT uk_url_hash UNIQUE (domain, url_hash);
+ALTER TABLE urls DROP COLUMN owner_id;
+
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,16 @@
+-- API Key 认证：只保存 key 的 SHA-256，明文仅在创建时展示一次
+CREATE TABLE api_keys (
+	id BIGSERIAL PRIMARY KEY,
+	owner_id VARCHAR(64) COLLATE "C" NOT NULL,
+	name VARCHAR(255) NOT NULL DEFAULT '',
+	key_hash CHAR(64) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	revoked_at TIMESTAMPTZ NULL,
+	CONSTRAINT uk_key_hash UNIQUE (key_hash)
+);
+
+-- 链接归属的用户 (空字符串表示未启用认证时创建)，去重改为在域名 + 用户内进行
+ALTER TABLE urls ADD COLUMN owner_id VARCHAR(64) COLLATE "C" NOT NULL DEFAULT '';
+ALTER TABLE urls DROP CONSTRAINT uk_url_hash;
+ALTER TABLE urls ADD CONSTRAINT uk_url_hash UNIQUE (domain, owner_id, url_hash);
+CREATE INDEX idx_owner ON urls (owner_id, id);
@@ -0,0 +1,7 @@
+-- 配额：按持有者和自然月 (UTC，period 形如 2026-01) 统计新建的链接数，删除链接不退还
+CREATE TABLE link_usage (
+	owner_id VARCHAR(64) COLLATE "C" NOT NULL,
+	period CHAR(7) NOT NULL,
+	links_created BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (owner_id, period)
+);
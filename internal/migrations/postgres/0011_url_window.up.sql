@@ -0,0 +1,3 @@
+-- 生效时间窗口：[not_before, not_after) 之外访问返回兜底地址或 404，NULL 表示不限
+ALTER TABLE urls ADD COLUMN not_before TIMESTAMPTZ NULL;
+ALTER TABLE urls ADD COLUMN not_after TIMESTAMPTZ NULL;
@@ -0,0 +1,2 @@
+-- 按设备平台跳转：平台 -> 目标地址的 JSON 对象，NULL 表示所有设备都跳转到 long_url
+ALTER TABLE urls ADD COLUMN targets TEXT NULL;
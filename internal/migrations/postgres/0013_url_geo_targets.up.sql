@@ -0,0 +1,2 @@
+-- 按访问者国家跳转：ISO 3166-1 国家代码 -> 目标地址的 JSON 对象，NULL 表示不按国家区分
+ALTER TABLE urls ADD COLUMN geo_targets TEXT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE urls DROP COLUMN pass_query;
+ALTER TABLE urls DROP COLUMN query_params;
@@ -0,0 +1,4 @@
+-- 跳转时合并到目标地址的查询参数 (如 UTM)：参数名 -> 值的 JSON 对象；
+-- pass_query 是否把访客附加在短链接上的查询参数透传给目标地址，NULL 表示沿用持有者的默认模板
+ALTER TABLE urls ADD COLUMN query_params TEXT NULL;
+ALTER TABLE urls ADD COLUMN pass_query BOOLEAN NULL;
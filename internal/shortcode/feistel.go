@@ -0,0 +1,88 @@
+// Package shortcode 提供短码 ID 的可逆置换，使短码看起来随机且无法按顺序枚举
+package shortcode
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+)
+
+// rounds Feistel 轮数，4 轮即可得到伪随机置换 (Luby-Rackoff)
+const rounds = 4
+
+// Feistel 基于密钥的平衡 Feistel 网络
+// 在 2^(2*halfBits) 空间上构成置换，再通过 cycle-walking 收缩到 [0, limit)，
+// 因此对 [0, limit) 内的输入既无碰撞又可逆
+type Feistel struct {
+	key      []byte
+	halfBits uint
+	mask     uint64
+	limit    uint64
+}
+
+// NewFeistel 创建在 [0, limit) 上的置换，limit 必须大于 1
+func NewFeistel(key []byte, limit uint64) *Feistel {
+	n := uint(bits.Len64(limit - 1))
+	if n%2 == 1 {
+		n++
+	}
+	half := n / 2
+	return &Feistel{
+		key:      key,
+		halfBits: half,
+		mask:     1<<half - 1,
+		limit:    limit,
+	}
+}
+
+// Limit 返回置换的定义域上界 (不含)
+func (f *Feistel) Limit() uint64 {
+	return f.limit
+}
+
+// Permute 将 x 映射为 [0, limit) 中的另一个数，x 必须小于 limit
+func (f *Feistel) Permute(x uint64) uint64 {
+	for {
+		x = f.encrypt(x)
+		if x < f.limit {
+			return x
+		}
+	}
+}
+
+// Invert 是 Permute 的逆运算
+func (f *Feistel) Invert(y uint64) uint64 {
+	for {
+		y = f.decrypt(y)
+		if y < f.limit {
+			return y
+		}
+	}
+}
+
+func (f *Feistel) encrypt(x uint64) uint64 {
+	l, r := x>>f.halfBits, x&f.mask
+	for i := 0; i < rounds; i++ {
+		l, r = r, l^f.round(i, r)
+	}
+	return l<<f.halfBits | r
+}
+
+func (f *Feistel) decrypt(y uint64) uint64 {
+	l, r := y>>f.halfBits, y&f.mask
+	for i := rounds - 1; i >= 0; i-- {
+		l, r = r^f.round(i, l), l
+	}
+	return l<<f.halfBits | r
+}
+
+// round 轮函数：HMAC-SHA256(key, 轮次 || 半块)，截取 halfBits 位
+func (f *Feistel) round(i int, half uint64) uint64 {
+	var buf [9]byte
+	buf[0] = byte(i)
+	binary.BigEndian.PutUint64(buf[1:], half)
+	mac := hmac.New(sha256.New, f.key)
+	mac.Write(buf[:])
+	return binary.BigEndian.Uint64(mac.Sum(nil)) & f.mask
+}
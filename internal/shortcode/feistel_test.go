@@ -0,0 +1,51 @@
+package shortcode
+
+import "testing"
+
+func TestFeistelBijection(t *testing.T) {
+	for _, limit := range []uint64{2, 7, 1000, 62 * 62, 1 << 12} {
+		f := NewFeistel([]byte("secret"), limit)
+		seen := make(map[uint64]bool, limit)
+		for x := uint64(0); x < limit; x++ {
+			y := f.Permute(x)
+			if y >= limit {
+				t.Fatalf("limit %d: Permute(%d) = %d, out of range", limit, x, y)
+			}
+			if seen[y] {
+				t.Fatalf("limit %d: Permute(%d) = %d collides", limit, x, y)
+			}
+			seen[y] = true
+			if got := f.Invert(y); got != x {
+				t.Fatalf("limit %d: Invert(Permute(%d)) = %d", limit, x, got)
+			}
+		}
+	}
+}
+
+func TestFeistelRoundTripLargeDomain(t *testing.T) {
+	const limit = 3521614606208 // 62^7
+	f := NewFeistel([]byte("secret"), limit)
+	for _, x := range []uint64{0, 1, 2, 1000, 123456789, limit / 2, limit - 1} {
+		y := f.Permute(x)
+		if y >= limit {
+			t.Errorf("Permute(%d) = %d, out of range", x, y)
+		}
+		if got := f.Invert(y); got != x {
+			t.Errorf("Invert(Permute(%d)) = %d", x, got)
+		}
+	}
+}
+
+func TestFeistelKeyed(t *testing.T) {
+	a := NewFeistel([]byte("key-a"), 1<<20)
+	b := NewFeistel([]byte("key-b"), 1<<20)
+	same := 0
+	for x := uint64(0); x < 100; x++ {
+		if a.Permute(x) == b.Permute(x) {
+			same++
+		}
+	}
+	if same > 5 {
+		t.Errorf("different keys produced %d identical outputs out of 100", same)
+	}
+}
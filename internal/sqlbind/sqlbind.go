@@ -0,0 +1,24 @@
+// Package sqlbind 在不同数据库的占位符格式之间转换
+package sqlbind
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dollar 将 ? 依次替换为 PostgreSQL 的 $1, $2, ...
+// 本项目的查询与迁移记录语句中 ? 不会出现在字符串字面量里
+func Dollar(query string) string {
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(query[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,20 @@
+package sqlbind
+
+import "testing"
+
+func TestDollar(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT * FROM urls WHERE id = ?", "SELECT * FROM urls WHERE id = $1"},
+		{"INSERT INTO t (a, b, c) VALUES (?, ?, ?)", "INSERT INTO t (a, b, c) VALUES ($1, $2, $3)"},
+		{"UPDATE urls SET long_url = ? WHERE domain = ? AND alias = ? LIMIT ?", "UPDATE urls SET long_url = $1 WHERE domain = $2 AND alias = $3 LIMIT $4"},
+		{"SELECT '链接' FROM t WHERE id = ?", "SELECT '链接' FROM t WHERE id = $1"},
+	}
+	for _, tt := range tests {
+		if got := Dollar(tt.query); got != tt.want {
+			t.Errorf("Dollar(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrAPIKeyNotFound 表示 API Key 不存在 (或已吊销)
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// apiKeyPrefix 便于在日志、代码仓库中识别泄露的 key
+const apiKeyPrefix = "tl_"
+
+// APIKey 对应 api_keys 表中的一行，只保存 key 的哈希
+type APIKey struct {
+	ID        int64
+	OwnerID   string // 持有者，创建的链接归属于该用户
+	Name      string // 备注
+	Hash      string // key 的 SHA-256
+	CreatedAt time.Time
+	RevokedAt *time.Time // 吊销时间，nil 表示有效
+}
+
+// Revoked 判断 key 是否已吊销
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// GenerateAPIKey 生成一个新的随机 API Key，返回明文和哈希
+func GenerateAPIKey() (key, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, HashAPIKey(key), nil
+}
+
+// HashAPIKey 计算 API Key 的存储哈希
+// key 本身是高熵随机串，无需加盐慢哈希，按哈希可直接走唯一索引查询
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyStore API Key 存储接口
+type APIKeyStore interface {
+	// CreateAPIKey 保存 key，成功后回填 ID 和创建时间
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	// GetAPIKeyByHash 根据哈希获取 key (含已吊销的)，不存在时返回 ErrAPIKeyNotFound
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	// ListAPIKeys 按 ID 升序列出全部 key
+	ListAPIKeys(ctx context.Context) ([]*APIKey, error)
+	// RevokeAPIKey 吊销 key，不存在或已吊销时返回 ErrAPIKeyNotFound
+	RevokeAPIKey(ctx context.Context, id int64) error
+}
+
+// apiKeyColumns 与 scanAPIKey 的扫描顺序一致
+const apiKeyColumns = "id, owner_id, name, key_hash, created_at, revoked_at"
+
+// CreateAPIKey 保存 key，成功后回填 ID 和创建时间
+func (s *SQLStore) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	key.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	id, err := s.d.insertID(ctx, s.db,
+		s.d.bind("INSERT INTO api_keys(owner_id, name, key_hash, created_at) VALUES (?, ?, ?, ?)"),
+		key.OwnerID, key.Name, key.Hash, key.CreatedAt)
+	if err != nil {
+		return err
+	}
+	key.ID = id
+	return nil
+}
+
+// GetAPIKeyByHash 根据哈希获取 key
+func (s *SQLStore) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	return scanAPIKey(s.db.QueryRowContext(ctx, s.d.bind("SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ?"), hash))
+}
+
+// ListAPIKeys 按 ID 升序列出全部 key
+func (s *SQLStore) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey 吊销 key，不存在或已吊销时返回 ErrAPIKeyNotFound
+func (s *SQLStore) RevokeAPIKey(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, s.d.bind("UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL"),
+		time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var (
+		key       APIKey
+		revokedAt sql.NullTime
+	)
+	err := row.Scan(&key.ID, &key.OwnerID, &key.Name, &key.Hash, &key.CreatedAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	key.CreatedAt = key.CreatedAt.UTC()
+	if revokedAt.Valid {
+		t := revokedAt.Time.UTC()
+		key.RevokedAt = &t
+	}
+	return &key, nil
+}
+
+// MemoryKeyStore 进程内的 APIKeyStore 实现，供单机模式使用
+type MemoryKeyStore struct {
+	mu     sync.RWMutex
+	keys   map[int64]*APIKey
+	hashes map[string]int64
+	nextID int64
+}
+
+// NewMemoryKeyStore 创建空的进程内 key 存储
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{
+		keys:   make(map[int64]*APIKey),
+		hashes: make(map[string]int64),
+	}
+}
+
+// CreateAPIKey 保存 key，成功后回填 ID 和创建时间
+func (s *MemoryKeyStore) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.hashes[key.Hash]; ok {
+		return errors.New("duplicate api key")
+	}
+	s.nextID++
+	key.ID = s.nextID
+	key.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	k := *key
+	s.keys[k.ID] = &k
+	s.hashes[k.Hash] = k.ID
+	return nil
+}
+
+// GetAPIKeyByHash 根据哈希获取 key
+func (s *MemoryKeyStore) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, ok := s.hashes[hash]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	k := *s.keys[id]
+	return &k, nil
+}
+
+// ListAPIKeys 按 ID 升序列出全部 key
+func (s *MemoryKeyStore) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]*APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		k := *key
+		keys = append(keys, &k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+// RevokeAPIKey 吊销 key，不存在或已吊销时返回 ErrAPIKeyNotFound
+func (s *MemoryKeyStore) RevokeAPIKey(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[id]
+	if !ok || key.Revoked() {
+		return ErrAPIKeyNotFound
+	}
+	now := time.Now().UTC()
+	key.RevokedAt = &now
+	return nil
+}
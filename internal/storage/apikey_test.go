@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, apiKeyPrefix) || len(key) != len(apiKeyPrefix)+43 {
+		t.Errorf("GenerateAPIKey = %q", key)
+	}
+	if hash != HashAPIKey(key) || len(hash) != 64 {
+		t.Errorf("hash = %q, want HashAPIKey(key)", hash)
+	}
+	if other, _, _ := GenerateAPIKey(); other == key {
+		t.Error("GenerateAPIKey returned the same key twice")
+	}
+}
+
+func TestMemoryKeyStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryKeyStore()
+	alice := &APIKey{OwnerID: "alice", Name: "ci", Hash: HashAPIKey("a")}
+	if err := s.CreateAPIKey(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	if alice.ID != 1 || alice.CreatedAt.IsZero() {
+		t.Errorf("CreateAPIKey did not fill ID and CreatedAt: %+v", alice)
+	}
+	if err := s.CreateAPIKey(ctx, &APIKey{OwnerID: "bob", Hash: HashAPIKey("a")}); err == nil {
+		t.Error("CreateAPIKey with a duplicate hash succeeded")
+	}
+	if err := s.CreateAPIKey(ctx, &APIKey{OwnerID: "bob", Hash: HashAPIKey("b")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if k, err := s.GetAPIKeyByHash(ctx, HashAPIKey("a")); err != nil || k.OwnerID != "alice" || k.Revoked() {
+		t.Errorf("GetAPIKeyByHash = %+v, %v", k, err)
+	}
+	if _, err := s.GetAPIKeyByHash(ctx, HashAPIKey("c")); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("GetAPIKeyByHash(unknown) = %v, want ErrAPIKeyNotFound", err)
+	}
+
+	if err := s.RevokeAPIKey(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RevokeAPIKey(ctx, alice.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("second RevokeAPIKey = %v, want ErrAPIKeyNotFound", err)
+	}
+	if err := s.RevokeAPIKey(ctx, 9); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("RevokeAPIKey(unknown) = %v, want ErrAPIKeyNotFound", err)
+	}
+	// 吊销的 key 仍可查到，由调用方拒绝
+	if k, err := s.GetAPIKeyByHash(ctx, HashAPIKey("a")); err != nil || !k.Revoked() {
+		t.Errorf("revoked key = %+v, %v", k, err)
+	}
+
+	keys, err := s.ListAPIKeys(ctx)
+	if err != nil || len(keys) != 2 || keys[0].OwnerID != "alice" || keys[1].OwnerID != "bob" {
+		t.Errorf("ListAPIKeys = %v, %v", keys, err)
+	}
+}
@@ -8,7 +8,18 @@ import (
 	"github.com/go-redis/redis/v8"
 )
 
+// Filter 短码存在性过滤器，Exists 返回 false 时数据一定不存在
+type Filter interface {
+	Add(data string) error
+	AddBatch(items []string) error
+	Exists(data string) (bool, error)
+	// ExistsMany 批量检查数据是否存在，结果与 items 一一对应
+	ExistsMany(items []string) ([]bool, error)
+}
+
+// BloomFilter 基于 Redis bitmap 的布隆过滤器
 type BloomFilter struct {
+	rdb         *redis.Client
 	Key         string
 	Size        uint
 	HashFuncNum uint
@@ -17,20 +28,10 @@ type BloomFilter struct {
 // NewBloomFilter 初始化一个布隆过滤器
 // n 预期的数据量
 // p 误判率
-func NewBloomFilter(key string, n uint, p float64) *BloomFilter {
-	sizeF := -float64(n) * math.Log(p) / (math.Log(2) * math.Log(2))
-	hashNumF := sizeF / float64(n) * math.Log(2)
-
-	size := uint(math.Ceil(sizeF))
-	if size == 0 {
-		size = 1
-	}
-	hashNum := uint(math.Ceil(hashNumF))
-	if hashNum == 0 {
-		hashNum = 1
-	}
-
+func NewBloomFilter(rdb *redis.Client, key string, n uint, p float64) *BloomFilter {
+	size, hashNum := bloomParams(n, p)
 	return &BloomFilter{
+		rdb:         rdb,
 		Key:         key,
 		Size:        size,
 		HashFuncNum: hashNum,
@@ -42,7 +43,7 @@ func (bf *BloomFilter) Add(data string) error {
 	locations := bf.getLocations(data)
 	ctx := context.Background()
 
-	pipe := Rdb.Pipeline()
+	pipe := bf.rdb.Pipeline()
 	for _, loc := range locations {
 		pipe.SetBit(ctx, bf.Key, int64(loc), 1)
 	}
@@ -50,12 +51,26 @@ func (bf *BloomFilter) Add(data string) error {
 	return err
 }
 
+// AddBatch 批量添加数据，所有位操作在一次 pipeline 中完成
+func (bf *BloomFilter) AddBatch(items []string) error {
+	ctx := context.Background()
+
+	pipe := bf.rdb.Pipeline()
+	for _, data := range items {
+		for _, loc := range bf.getLocations(data) {
+			pipe.SetBit(ctx, bf.Key, int64(loc), 1)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
 // Exists 检查数据是否存在
 func (bf *BloomFilter) Exists(data string) (bool, error) {
 	locations := bf.getLocations(data)
 	ctx := context.Background()
 
-	pipe := Rdb.Pipeline()
+	pipe := bf.rdb.Pipeline()
 	for _, loc := range locations {
 		pipe.GetBit(ctx, bf.Key, int64(loc))
 	}
@@ -79,20 +94,77 @@ func (bf *BloomFilter) Exists(data string) (bool, error) {
 	return true, nil
 }
 
-// getLocations 计算数据映射到的bitmap位置
-// FNV哈希算法，双重哈希模拟k个哈希函数
+// ExistsMany 批量检查数据是否存在，所有位读取在一次 pipeline 中完成
+func (bf *BloomFilter) ExistsMany(items []string) ([]bool, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	ctx := context.Background()
+
+	pipe := bf.rdb.Pipeline()
+	for _, data := range items {
+		for _, loc := range bf.getLocations(data) {
+			pipe.GetBit(ctx, bf.Key, int64(loc))
+		}
+	}
+	cmds, err := pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每个数据对应连续的 HashFuncNum 个位，全部为 1 时才可能存在
+	exists := make([]bool, len(items))
+	k := int(bf.HashFuncNum)
+	for i := range items {
+		exists[i] = true
+		for _, cmd := range cmds[i*k : (i+1)*k] {
+			val, err := cmd.(*redis.IntCmd).Result()
+			if err != nil {
+				return nil, err
+			}
+			if val == 0 {
+				exists[i] = false
+				break
+			}
+		}
+	}
+	return exists, nil
+}
+
+// bloomParams 根据预期数据量 n 和误判率 p 计算 bitmap 大小和哈希函数个数
+func bloomParams(n uint, p float64) (uint, uint) {
+	sizeF := -float64(n) * math.Log(p) / (math.Log(2) * math.Log(2))
+	hashNumF := sizeF / float64(n) * math.Log(2)
+
+	size := uint(math.Ceil(sizeF))
+	if size == 0 {
+		size = 1
+	}
+	hashNum := uint(math.Ceil(hashNumF))
+	if hashNum == 0 {
+		hashNum = 1
+	}
+	return size, hashNum
+}
+
 func (bf *BloomFilter) getLocations(data string) []uint {
+	return bloomLocations(data, bf.Size, bf.HashFuncNum)
+}
+
+// bloomLocations 计算数据映射到的bitmap位置
+// FNV哈希算法，双重哈希模拟k个哈希函数
+func bloomLocations(data string, size, hashFuncNum uint) []uint {
 	bytes := []byte(data)
-	locations := make([]uint, int(bf.HashFuncNum))
+	locations := make([]uint, int(hashFuncNum))
 
 	// 计算两个基础哈希值
 	h1 := fnvHash(bytes)
 	h2 := fnvHash(append(bytes, byte(1))) // 简单的变种
 
-	for i := uint(0); i < bf.HashFuncNum; i++ {
+	for i := uint(0); i < hashFuncNum; i++ {
 		// 公式：hash_i = (h1 + i * h2) % size
 		// 这是一个生成多个哈希值的标准技巧
-		loc := (h1 + uint32(i)*h2) % uint32(bf.Size)
+		loc := (h1 + uint32(i)*h2) % uint32(size)
 		locations[i] = uint(loc)
 	}
 	return locations
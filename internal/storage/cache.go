@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrCacheMiss 表示缓存中不存在该键
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache 键值缓存接口，ttl 为 0 表示永不过期
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetNX 仅在键不存在时写入，返回是否写入成功
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	Del(ctx context.Context, key string) error
+}
+
+// RedisCache 基于 Redis 的缓存
+type RedisCache struct {
+	rdb *redis.Client
+}
+
+// NewRedisCache 使用已连接的 Redis 客户端创建缓存
+func NewRedisCache(rdb *redis.Client) *RedisCache {
+	return &RedisCache{rdb: rdb}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (string, error) {
+	val, err := c.rdb.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrCacheMiss
+	}
+	return val, err
+}
+
+func (c *RedisCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return c.rdb.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *RedisCache) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return c.rdb.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (c *RedisCache) Del(ctx context.Context, key string) error {
+	return c.rdb.Del(ctx, key).Err()
+}
+
+// memorySweepEvery 每写入多少次清理一遍已过期的键
+const memorySweepEvery = 1024
+
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time // 零值表示永不过期
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// MemoryCache 进程内缓存，供单机模式使用
+// 过期键在读取时惰性删除，并每隔 memorySweepEvery 次写入整体清理一次
+type MemoryCache struct {
+	mu     sync.Mutex
+	items  map[string]memoryEntry
+	writes int
+}
+
+// NewMemoryCache 创建进程内缓存
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{items: make(map[string]memoryEntry)}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return "", ErrCacheMiss
+	}
+	if e.expired(time.Now()) {
+		delete(c.items, key)
+		return "", ErrCacheMiss
+	}
+	return e.value, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, ttl, time.Now())
+	return nil
+}
+
+func (c *MemoryCache) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if e, ok := c.items[key]; ok && !e.expired(now) {
+		return false, nil
+	}
+	c.set(key, value, ttl, now)
+	return true, nil
+}
+
+// set 写入键值，调用方需持有锁
+func (c *MemoryCache) set(key, value string, ttl time.Duration, now time.Time) {
+	e := memoryEntry{value: value}
+	if ttl > 0 {
+		e.expiresAt = now.Add(ttl)
+	}
+	c.items[key] = e
+
+	c.writes++
+	if c.writes%memorySweepEvery == 0 {
+		for k, v := range c.items {
+			if v.expired(now) {
+				delete(c.items, k)
+			}
+		}
+	}
+}
+
+func (c *MemoryCache) Del(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+	return nil
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrCounterMissing 表示计数器中没有该链接的计数，需先以持久化的访问次数调用 Seed
+var ErrCounterMissing = errors.New("click counter missing")
+
+// ClickCounter 限次链接的访问计数器，位于数据库之前承担每次跳转的计数
+type ClickCounter interface {
+	// Claim 原子地为链接计入一次访问，计数已达 max 时返回 false
+	// 计数不存在 (首次访问或已过期) 时返回 ErrCounterMissing
+	Claim(ctx context.Context, id, max int64) (bool, error)
+	// Seed 在计数不存在时以 clicks 初始化，ttl 后过期 (过期后重新从数据库初始化)
+	Seed(ctx context.Context, id, clicks int64, ttl time.Duration) error
+}
+
+// claimScript 计数未达上限时加一，返回 1 放行、0 已用完、-1 计数不存在
+var claimScript = redis.NewScript(`
+local n = tonumber(redis.call('GET', KEYS[1]))
+if n == nil then
+	return -1
+end
+if n >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('INCR', KEYS[1])
+return 1
+`)
+
+// RedisClickCounter 基于 Redis 的访问计数，多个 API 实例共享
+type RedisClickCounter struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+// NewRedisClickCounter 创建计数器，计数保存在 prefix + 链接 ID 下
+func NewRedisClickCounter(rdb *redis.Client, prefix string) *RedisClickCounter {
+	return &RedisClickCounter{rdb: rdb, prefix: prefix}
+}
+
+func (c *RedisClickCounter) key(id int64) string {
+	return c.prefix + strconv.FormatInt(id, 10)
+}
+
+func (c *RedisClickCounter) Claim(ctx context.Context, id, max int64) (bool, error) {
+	n, err := claimScript.Run(ctx, c.rdb, []string{c.key(id)}, max).Int64()
+	if err != nil {
+		return false, err
+	}
+	if n < 0 {
+		return false, ErrCounterMissing
+	}
+	return n == 1, nil
+}
+
+func (c *RedisClickCounter) Seed(ctx context.Context, id, clicks int64, ttl time.Duration) error {
+	return c.rdb.SetNX(ctx, c.key(id), clicks, ttl).Err()
+}
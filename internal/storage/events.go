@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"context"
+	"log"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// EventSink 点击事件的投递目标
+type EventSink interface {
+	Publish(ctx context.Context, payload []byte) error
+}
+
+// KafkaSink 将事件写入 Kafka
+type KafkaSink struct {
+	w *kafka.Writer
+}
+
+// NewKafkaSink 使用已创建的 Kafka Writer 创建事件投递目标
+func NewKafkaSink(w *kafka.Writer) *KafkaSink {
+	return &KafkaSink{w: w}
+}
+
+func (s *KafkaSink) Publish(ctx context.Context, payload []byte) error {
+	return s.w.WriteMessages(ctx, kafka.Message{Value: payload})
+}
+
+// LogSink 将事件打印到标准日志，供单机模式使用
+type LogSink struct{}
+
+func (LogSink) Publish(ctx context.Context, payload []byte) error {
+	log.Printf("click event: %s", payload)
+	return nil
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound 表示链接不存在
+	ErrNotFound = errors.New("link not found")
+	// ErrAliasTaken 表示自定义别名已被占用
+	ErrAliasTaken = errors.New("alias already taken")
+	// ErrURLExists 表示域名下该用户已有相同长链接的去重链接
+	ErrURLExists = errors.New("url already shortened")
+)
+
+// Link 对应 urls 表中的一行
+type Link struct {
+	ID           int64
+	LongURL      string
+	Targets      map[string]string // 按设备平台选择的目标地址，未匹配的平台跳转到 LongURL
+	GeoTargets   map[string]string // 按访问者国家 (ISO 3166-1 代码) 选择的目标地址，优先于 Targets
+	Variants     []Variant         // A/B 分流的目标地址，设置后按权重代替 LongURL
+	QueryParams  map[string]string // 跳转时合并到目标地址的查询参数 (如 UTM)，值中可使用占位符
+	PassQuery    *bool             // 是否透传访客附加在短链接上的查询参数，nil 表示沿用持有者的默认模板
+	Domain       string            // 所属域名 (小写 host)，空字符串表示默认域名
+	OwnerID      string            // 创建者，空字符串表示未启用认证时创建
+	Alias        string            // 自定义别名 (在域名内唯一)，为空表示使用 ID 编码的短码
+	URLHash      string            // 去重模式下长链接的 SHA-256 (在域名 + 创建者内唯一)，为空表示不参与去重
+	PasswordHash string            // 访问密码的 bcrypt 哈希，为空表示无需密码
+	MaxClicks    int64             // 允许的访问次数，0 表示不限
+	Clicks       int64             // 限次链接已计入的访问次数
+	NotBefore    *time.Time        // 生效时间 (UTC)，nil 表示创建后立即生效
+	NotAfter     *time.Time        // 停止生效时间 (UTC)，nil 表示不限；与过期不同，窗口外的链接仍保留
+	ExpiresAt    *time.Time        // 过期时间 (UTC)，nil 表示永不过期
+	CreatedAt    time.Time
+}
+
+// Variant A/B 分流链接的一个分组
+type Variant struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"` // 相对权重，分到该组的概率为 Weight / 所有分组 Weight 之和
+}
+
+// Expired 判断链接在 now 时刻是否已过期
+func (l *Link) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// Exhausted 判断限次链接的访问次数是否已用完
+func (l *Link) Exhausted() bool {
+	return l.MaxClicks > 0 && l.Clicks >= l.MaxClicks
+}
+
+// Active 判断 now 是否处于链接的生效时间窗口 [NotBefore, NotAfter) 内
+func (l *Link) Active(now time.Time) bool {
+	return (l.NotBefore == nil || !now.Before(*l.NotBefore)) && (l.NotAfter == nil || now.Before(*l.NotAfter))
+}
+
+// CacheTTL 返回链接的跳转结果 (生效、未生效或过期) 保持不变的时长，即距下一个
+// 生效、停止生效或过期时刻的时间，用作缓存有效期；此后不再变化时返回 0 (与 Redis 的 "不过期" 语义一致)
+func (l *Link) CacheTTL(now time.Time) time.Duration {
+	var ttl time.Duration
+	for _, t := range []*time.Time{l.NotBefore, l.NotAfter, l.ExpiresAt} {
+		if t != nil && t.After(now) && (ttl == 0 || t.Sub(now) < ttl) {
+			ttl = t.Sub(now)
+		}
+	}
+	return ttl
+}
+
+// ListOptions 分页查询参数，按 ID 升序游标分页
+type ListOptions struct {
+	AfterID int64 // 只返回 ID 大于该值的链接
+	Limit   int
+	OwnerID *string // 非 nil 时只返回该用户创建的链接
+}
+
+// LinkStore 链接存储接口，屏蔽具体的存储后端
+// 查询不到时返回 ErrNotFound，别名冲突时返回 ErrAliasTaken，长链接哈希冲突时返回 ErrURLExists
+type LinkStore interface {
+	// SaveLink 保存单个链接
+	SaveLink(ctx context.Context, link *Link) error
+	// SaveLinks 批量保存链接，要么全部成功要么全部失败
+	SaveLinks(ctx context.Context, links []*Link) error
+	// GetLink 根据 ID 获取链接
+	GetLink(ctx context.Context, id int64) (*Link, error)
+	// GetLinkByAlias 根据域名和自定义别名获取链接
+	GetLinkByAlias(ctx context.Context, domain, alias string) (*Link, error)
+	// FindAliases 返回 aliases 中在域名内已被使用的别名
+	FindAliases(ctx context.Context, domain string, aliases []string) ([]string, error)
+	// GetLinkByURLHash 根据域名、创建者和长链接哈希获取去重链接
+	GetLinkByURLHash(ctx context.Context, domain, ownerID, hash string) (*Link, error)
+	// UpdateLink 更新链接中可通过管理接口修改的字段 (目标地址与跳转规则、访问限制、生效时间和过期时间) 及长链接哈希，链接不存在时返回 ErrNotFound
+	UpdateLink(ctx context.Context, link *Link) error
+	// ClaimClick 原子地为链接计入一次访问，访问次数已达上限 (或链接不存在) 时返回 false
+	ClaimClick(ctx context.Context, id int64) (bool, error)
+	// DeleteLink 删除链接
+	DeleteLink(ctx context.Context, id int64) error
+	// ListLinks 分页列出链接
+	ListLinks(ctx context.Context, opts ListOptions) ([]*Link, error)
+	// DeleteExpired 删除在 before 之前过期的链接，最多 limit 行，返回删除行数
+	DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error)
+}
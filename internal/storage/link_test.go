@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func at(t time.Time) *time.Time { return &t }
+
+func TestLinkActive(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		notBefore *time.Time
+		notAfter  *time.Time
+		want      bool
+	}{
+		{"no window", nil, nil, true},
+		{"started", at(now.Add(-time.Hour)), nil, true},
+		{"starts exactly now", at(now), nil, true},
+		{"not started", at(now.Add(time.Second)), nil, false},
+		{"not ended", nil, at(now.Add(time.Second)), true},
+		{"ends exactly now", nil, at(now), false},
+		{"ended", nil, at(now.Add(-time.Hour)), false},
+		{"inside window", at(now.Add(-time.Hour)), at(now.Add(time.Hour)), true},
+		{"before window", at(now.Add(time.Hour)), at(now.Add(2 * time.Hour)), false},
+		{"after window", at(now.Add(-2 * time.Hour)), at(now.Add(-time.Hour)), false},
+	}
+	for _, tt := range tests {
+		l := &Link{NotBefore: tt.notBefore, NotAfter: tt.notAfter}
+		if got := l.Active(now); got != tt.want {
+			t.Errorf("%s: Active = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLinkCacheTTL(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                           string
+		notBefore, notAfter, expiresAt *time.Time
+		want                           time.Duration
+	}{
+		{"nothing scheduled", nil, nil, nil, 0},
+		{"all in the past", at(now.Add(-3 * time.Hour)), at(now.Add(-2 * time.Hour)), at(now.Add(-time.Hour)), 0},
+		{"expires", nil, nil, at(now.Add(time.Hour)), time.Hour},
+		{"starts", at(now.Add(10 * time.Minute)), nil, nil, 10 * time.Minute},
+		{"ends before expiry", nil, at(now.Add(time.Hour)), at(now.Add(2 * time.Hour)), time.Hour},
+		{"starts, ends and expires", at(now.Add(time.Minute)), at(now.Add(time.Hour)), at(now.Add(2 * time.Hour)), time.Minute},
+		{"started, ends later", at(now.Add(-time.Minute)), at(now.Add(30 * time.Minute)), nil, 30 * time.Minute},
+		{"boundary exactly now is past", at(now), at(now.Add(time.Hour)), nil, time.Hour},
+	}
+	for _, tt := range tests {
+		l := &Link{NotBefore: tt.notBefore, NotAfter: tt.notAfter, ExpiresAt: tt.expiresAt}
+		if got := l.CacheTTL(now); got != tt.want {
+			t.Errorf("%s: CacheTTL = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLinkExpired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expiresAt *time.Time
+		want      bool
+	}{
+		{nil, false},
+		{at(now.Add(time.Second)), false},
+		{at(now), true},
+		{at(now.Add(-time.Second)), true},
+	}
+	for _, tt := range tests {
+		l := &Link{ExpiresAt: tt.expiresAt}
+		if got := l.Expired(now); got != tt.want {
+			t.Errorf("Expired(expires_at=%v) = %v, want %v", tt.expiresAt, got, tt.want)
+		}
+	}
+}
+
+func TestLinkExhausted(t *testing.T) {
+	tests := []struct {
+		maxClicks, clicks int64
+		want              bool
+	}{
+		{0, 0, false},
+		{0, 100, false},
+		{3, 2, false},
+		{3, 3, true},
+		{3, 4, true},
+	}
+	for _, tt := range tests {
+		l := &Link{MaxClicks: tt.maxClicks, Clicks: tt.clicks}
+		if got := l.Exhausted(); got != tt.want {
+			t.Errorf("Exhausted(max=%d, clicks=%d) = %v, want %v", tt.maxClicks, tt.clicks, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore 进程内的 LinkStore / UsageStore 实现，供单机模式使用，进程退出后数据丢失
+type MemoryStore struct {
+	mu        sync.RWMutex
+	links     map[int64]*Link
+	aliases   map[domainKey]int64
+	urlHashes map[domainKey]int64
+	usage     map[usageKey]int64
+}
+
+// domainKey 别名在域名内唯一 (owner 为空)，长链接哈希在域名 + 创建者内唯一
+type domainKey struct {
+	domain, owner, key string
+}
+
+// NewMemoryStore 创建空的进程内存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		links:     make(map[int64]*Link),
+		aliases:   make(map[domainKey]int64),
+		urlHashes: make(map[domainKey]int64),
+		usage:     make(map[usageKey]int64),
+	}
+}
+
+// SaveLink 保存链接，别名冲突时返回 ErrAliasTaken，长链接哈希冲突时返回 ErrURLExists
+func (s *MemoryStore) SaveLink(ctx context.Context, link *Link) error {
+	return s.SaveLinks(ctx, []*Link{link})
+}
+
+// SaveLinks 批量保存链接，任一唯一键冲突时全部不写入
+func (s *MemoryStore) SaveLinks(ctx context.Context, links []*Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seenAliases := make(map[domainKey]struct{}, len(links))
+	seenHashes := make(map[domainKey]struct{}, len(links))
+	for _, link := range links {
+		if !claim(s.aliases, seenAliases, domainKey{link.Domain, "", link.Alias}) {
+			return ErrAliasTaken
+		}
+		if !claim(s.urlHashes, seenHashes, domainKey{link.Domain, link.OwnerID, link.URLHash}) {
+			return ErrURLExists
+		}
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, link := range links {
+		l := *link
+		if l.CreatedAt.IsZero() {
+			l.CreatedAt = now
+		}
+		s.links[l.ID] = &l
+		s.index(&l)
+	}
+	return nil
+}
+
+// claim 检查唯一键未被占用 (空键不参与唯一约束)，并记入本批次已用集合
+func claim(index map[domainKey]int64, seen map[domainKey]struct{}, k domainKey) bool {
+	if k.key == "" {
+		return true
+	}
+	if _, ok := index[k]; ok {
+		return false
+	}
+	if _, ok := seen[k]; ok {
+		return false
+	}
+	seen[k] = struct{}{}
+	return true
+}
+
+// index 将链接的别名、长链接哈希加入索引
+func (s *MemoryStore) index(link *Link) {
+	if link.Alias != "" {
+		s.aliases[domainKey{link.Domain, "", link.Alias}] = link.ID
+	}
+	if link.URLHash != "" {
+		s.urlHashes[domainKey{link.Domain, link.OwnerID, link.URLHash}] = link.ID
+	}
+}
+
+// unindex 将链接的别名、长链接哈希移出索引
+func (s *MemoryStore) unindex(link *Link) {
+	if link.Alias != "" {
+		delete(s.aliases, domainKey{link.Domain, "", link.Alias})
+	}
+	if link.URLHash != "" {
+		delete(s.urlHashes, domainKey{link.Domain, link.OwnerID, link.URLHash})
+	}
+}
+
+// GetLink 根据 ID 获取链接
+func (s *MemoryStore) GetLink(ctx context.Context, id int64) (*Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	link, ok := s.links[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	l := *link
+	return &l, nil
+}
+
+// GetLinkByAlias 根据域名和自定义别名获取链接
+func (s *MemoryStore) GetLinkByAlias(ctx context.Context, domain, alias string) (*Link, error) {
+	s.mu.RLock()
+	id, ok := s.aliases[domainKey{domain, "", alias}]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.GetLink(ctx, id)
+}
+
+// FindAliases 返回 aliases 中在域名内已被使用的别名
+func (s *MemoryStore) FindAliases(ctx context.Context, domain string, aliases []string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var found []string
+	for _, alias := range aliases {
+		if _, ok := s.aliases[domainKey{domain, "", alias}]; ok {
+			found = append(found, alias)
+		}
+	}
+	return found, nil
+}
+
+// GetLinkByURLHash 根据域名、创建者和长链接哈希获取去重链接
+func (s *MemoryStore) GetLinkByURLHash(ctx context.Context, domain, ownerID, hash string) (*Link, error) {
+	s.mu.RLock()
+	id, ok := s.urlHashes[domainKey{domain, ownerID, hash}]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.GetLink(ctx, id)
+}
+
+// UpdateLink 更新链接中可通过管理接口修改的字段 (目标地址与跳转规则、访问限制、生效时间和过期时间) 及长链接哈希
+func (s *MemoryStore) UpdateLink(ctx context.Context, link *Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.links[link.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if link.URLHash != "" && link.URLHash != stored.URLHash {
+		if _, taken := s.urlHashes[domainKey{stored.Domain, stored.OwnerID, link.URLHash}]; taken {
+			return ErrURLExists
+		}
+	}
+	s.unindex(stored)
+	stored.LongURL = link.LongURL
+	stored.Targets = link.Targets
+	stored.GeoTargets = link.GeoTargets
+	stored.Variants = link.Variants
+	stored.QueryParams = link.QueryParams
+	stored.PassQuery = link.PassQuery
+	stored.URLHash = link.URLHash
+	stored.PasswordHash = link.PasswordHash
+	stored.MaxClicks = link.MaxClicks
+	stored.NotBefore = link.NotBefore
+	stored.NotAfter = link.NotAfter
+	stored.ExpiresAt = link.ExpiresAt
+	s.index(stored)
+	return nil
+}
+
+// ClaimClick 原子地计入一次访问
+func (s *MemoryStore) ClaimClick(ctx context.Context, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, ok := s.links[id]
+	if !ok || link.Exhausted() {
+		return false, nil
+	}
+	link.Clicks++
+	return true, nil
+}
+
+// DeleteLink 删除链接，不存在时返回 ErrNotFound
+func (s *MemoryStore) DeleteLink(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, ok := s.links[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.links, id)
+	s.unindex(link)
+	return nil
+}
+
+// ListLinks 按 ID 升序分页列出链接
+func (s *MemoryStore) ListLinks(ctx context.Context, opts ListOptions) ([]*Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]int64, 0, len(s.links))
+	for id, link := range s.links {
+		if id > opts.AfterID && (opts.OwnerID == nil || link.OwnerID == *opts.OwnerID) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > opts.Limit {
+		ids = ids[:opts.Limit]
+	}
+
+	links := make([]*Link, len(ids))
+	for i, id := range ids {
+		l := *s.links[id]
+		links[i] = &l
+	}
+	return links, nil
+}
+
+// DeleteExpired 删除在 before 之前过期的链接，最多 limit 行
+func (s *MemoryStore) DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, link := range s.links {
+		if n >= int64(limit) {
+			break
+		}
+		if link.ExpiresAt != nil && link.ExpiresAt.Before(before) {
+			delete(s.links, id)
+			s.unindex(link)
+			n++
+		}
+	}
+	return n, nil
+}
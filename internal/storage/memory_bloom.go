@@ -0,0 +1,66 @@
+package storage
+
+import "sync"
+
+// MemoryBloomFilter 进程内布隆过滤器，供单机模式使用
+type MemoryBloomFilter struct {
+	mu          sync.RWMutex
+	bits        []uint64
+	size        uint
+	hashFuncNum uint
+}
+
+// NewMemoryBloomFilter 初始化进程内布隆过滤器，参数含义同 NewBloomFilter
+func NewMemoryBloomFilter(n uint, p float64) *MemoryBloomFilter {
+	size, hashNum := bloomParams(n, p)
+	return &MemoryBloomFilter{
+		bits:        make([]uint64, (size+63)/64),
+		size:        size,
+		hashFuncNum: hashNum,
+	}
+}
+
+// Add 向布隆过滤器添加数据
+func (bf *MemoryBloomFilter) Add(data string) error {
+	return bf.AddBatch([]string{data})
+}
+
+// AddBatch 批量添加数据
+func (bf *MemoryBloomFilter) AddBatch(items []string) error {
+	bf.mu.Lock()
+	defer bf.mu.Unlock()
+	for _, data := range items {
+		for _, loc := range bloomLocations(data, bf.size, bf.hashFuncNum) {
+			bf.bits[loc/64] |= 1 << (loc % 64)
+		}
+	}
+	return nil
+}
+
+// Exists 检查数据是否存在
+func (bf *MemoryBloomFilter) Exists(data string) (bool, error) {
+	bf.mu.RLock()
+	defer bf.mu.RUnlock()
+	return bf.exists(data), nil
+}
+
+// ExistsMany 批量检查数据是否存在
+func (bf *MemoryBloomFilter) ExistsMany(items []string) ([]bool, error) {
+	bf.mu.RLock()
+	defer bf.mu.RUnlock()
+	exists := make([]bool, len(items))
+	for i, data := range items {
+		exists[i] = bf.exists(data)
+	}
+	return exists, nil
+}
+
+// exists 检查数据对应的所有位是否都为 1，调用方需持有读锁
+func (bf *MemoryBloomFilter) exists(data string) bool {
+	for _, loc := range bloomLocations(data, bf.size, bf.hashFuncNum) {
+		if bf.bits[loc/64]&(1<<(loc%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMemoryBloomFilter(t *testing.T) {
+	bf := NewMemoryBloomFilter(1000, 0.01)
+	var added []string
+	for i := range 100 {
+		added = append(added, fmt.Sprintf("code%d", i))
+	}
+	if err := bf.AddBatch(added[:99]); err != nil {
+		t.Fatal(err)
+	}
+	if err := bf.Add(added[99]); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已添加的数据一定存在
+	for _, data := range added {
+		if ok, _ := bf.Exists(data); !ok {
+			t.Errorf("Exists(%q) = false after Add", data)
+		}
+	}
+	exists, err := bf.ExistsMany(append([]string{"missing"}, added...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, ok := range exists[1:] {
+		if !ok {
+			t.Errorf("ExistsMany[%q] = false after Add", added[i])
+		}
+	}
+	if ok, _ := bf.Exists("missing"); ok != exists[0] {
+		t.Errorf("Exists(missing) = %v, ExistsMany = %v", ok, exists[0])
+	}
+
+	// 误判率接近配置值
+	var falsePositives int
+	for i := range 1000 {
+		if ok, _ := bf.Exists(fmt.Sprintf("other%d", i)); ok {
+			falsePositives++
+		}
+	}
+	if falsePositives > 30 {
+		t.Errorf("%d false positives in 1000 lookups", falsePositives)
+	}
+}
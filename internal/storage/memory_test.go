@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreSaveAndGet(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	if err := s.SaveLink(ctx, &Link{ID: 1, LongURL: "https://example.com/a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	link, err := s.GetLink(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.LongURL != "https://example.com/a" || link.CreatedAt.IsZero() {
+		t.Errorf("GetLink = %+v", link)
+	}
+	// 返回的是副本，修改不影响存储
+	link.LongURL = "https://changed.example"
+	if again, _ := s.GetLink(ctx, 1); again.LongURL != "https://example.com/a" {
+		t.Errorf("stored link modified through returned copy: %q", again.LongURL)
+	}
+
+	if _, err := s.GetLink(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetLink(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStoreAliases(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	if err := s.SaveLink(ctx, &Link{ID: 1, LongURL: "https://a.example", Alias: "promo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		link *Link
+		want error
+	}{
+		{"same alias, same domain", &Link{ID: 2, Alias: "promo"}, ErrAliasTaken},
+		{"alias is case sensitive", &Link{ID: 3, Alias: "Promo"}, nil},
+		{"same alias, other domain", &Link{ID: 4, Alias: "promo", Domain: "go.example"}, nil},
+		{"no alias", &Link{ID: 5}, nil},
+		{"another link without alias", &Link{ID: 6}, nil},
+	}
+	for _, tt := range tests {
+		if err := s.SaveLink(ctx, tt.link); !errors.Is(err, tt.want) {
+			t.Errorf("%s: SaveLink = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if link, err := s.GetLinkByAlias(ctx, "", "promo"); err != nil || link.ID != 1 {
+		t.Errorf("GetLinkByAlias(promo) = %v, %v", link, err)
+	}
+	if link, err := s.GetLinkByAlias(ctx, "go.example", "promo"); err != nil || link.ID != 4 {
+		t.Errorf("GetLinkByAlias(go.example, promo) = %v, %v", link, err)
+	}
+	if _, err := s.GetLinkByAlias(ctx, "", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetLinkByAlias(missing) = %v, want ErrNotFound", err)
+	}
+	if found, err := s.FindAliases(ctx, "", []string{"promo", "missing", "Promo"}); err != nil || len(found) != 2 || found[0] != "promo" || found[1] != "Promo" {
+		t.Errorf("FindAliases = %v, %v; want [promo Promo]", found, err)
+	}
+}
+
+func TestMemoryStoreSaveLinksIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	if err := s.SaveLink(ctx, &Link{ID: 1, Alias: "taken"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		links []*Link
+		want  error
+	}{
+		{"conflict with stored alias", []*Link{{ID: 10}, {ID: 11, Alias: "taken"}}, ErrAliasTaken},
+		{"conflict inside the batch", []*Link{{ID: 12, Alias: "dup"}, {ID: 13, Alias: "dup"}}, ErrAliasTaken},
+		{"hash conflict inside the batch", []*Link{{ID: 14, URLHash: "h"}, {ID: 15, URLHash: "h"}}, ErrURLExists},
+	}
+	for _, tt := range tests {
+		if err := s.SaveLinks(ctx, tt.links); !errors.Is(err, tt.want) {
+			t.Errorf("%s: SaveLinks = %v, want %v", tt.name, err, tt.want)
+		}
+		for _, l := range tt.links {
+			if _, err := s.GetLink(ctx, l.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s: link %d saved despite the failed batch", tt.name, l.ID)
+			}
+		}
+	}
+}
+
+func TestMemoryStoreURLHash(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	if err := s.SaveLink(ctx, &Link{ID: 1, OwnerID: "alice", URLHash: "h1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		link *Link
+		want error
+	}{
+		{"same owner, same domain", &Link{ID: 2, OwnerID: "alice", URLHash: "h1"}, ErrURLExists},
+		{"other owner", &Link{ID: 3, OwnerID: "bob", URLHash: "h1"}, nil},
+		{"other domain", &Link{ID: 4, OwnerID: "alice", URLHash: "h1", Domain: "go.example"}, nil},
+	}
+	for _, tt := range tests {
+		if err := s.SaveLink(ctx, tt.link); !errors.Is(err, tt.want) {
+			t.Errorf("%s: SaveLink = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if link, err := s.GetLinkByURLHash(ctx, "", "alice", "h1"); err != nil || link.ID != 1 {
+		t.Errorf("GetLinkByURLHash(alice) = %v, %v", link, err)
+	}
+	if link, err := s.GetLinkByURLHash(ctx, "", "bob", "h1"); err != nil || link.ID != 3 {
+		t.Errorf("GetLinkByURLHash(bob) = %v, %v", link, err)
+	}
+}
+
+func TestMemoryStoreUpdateLink(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	links := []*Link{
+		{ID: 1, LongURL: "https://a.example", Alias: "a", URLHash: "ha"},
+		{ID: 2, LongURL: "https://b.example", URLHash: "hb"},
+	}
+	if err := s.SaveLinks(ctx, links); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.UpdateLink(ctx, &Link{ID: 1, LongURL: "https://x.example", URLHash: "hb"}); !errors.Is(err, ErrURLExists) {
+		t.Errorf("UpdateLink to a used hash = %v, want ErrURLExists", err)
+	}
+	if err := s.UpdateLink(ctx, &Link{ID: 9}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateLink(missing) = %v, want ErrNotFound", err)
+	}
+
+	update := &Link{ID: 1, LongURL: "https://c.example", URLHash: "hc", MaxClicks: 5, Targets: map[string]string{"ios": "https://ios.example"},
+		Alias: "ignored", Domain: "ignored.example"}
+	if err := s.UpdateLink(ctx, update); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := s.GetLink(ctx, 1)
+	if got.LongURL != "https://c.example" || got.MaxClicks != 5 || got.Targets["ios"] != "https://ios.example" {
+		t.Errorf("updated link = %+v", got)
+	}
+	// 别名与域名不可修改
+	if got.Alias != "a" || got.Domain != "" {
+		t.Errorf("UpdateLink changed alias/domain to %q/%q", got.Alias, got.Domain)
+	}
+	// 长链接哈希索引随之更新
+	if _, err := s.GetLinkByURLHash(ctx, "", "", "ha"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("old hash still indexed: %v", err)
+	}
+	if link, err := s.GetLinkByURLHash(ctx, "", "", "hc"); err != nil || link.ID != 1 {
+		t.Errorf("GetLinkByURLHash(hc) = %v, %v", link, err)
+	}
+	if link, err := s.GetLinkByAlias(ctx, "", "a"); err != nil || link.ID != 1 {
+		t.Errorf("alias lost after update: %v, %v", link, err)
+	}
+}
+
+func TestMemoryStoreClaimClick(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	if err := s.SaveLinks(ctx, []*Link{{ID: 1, MaxClicks: 2}, {ID: 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []bool{true, true, false, false} {
+		if ok, err := s.ClaimClick(ctx, 1); err != nil || ok != want {
+			t.Errorf("click %d on limited link = %v, %v; want %v", i+1, ok, err, want)
+		}
+	}
+	if link, _ := s.GetLink(ctx, 1); link.Clicks != 2 || !link.Exhausted() {
+		t.Errorf("limited link clicks = %d, exhausted = %v", link.Clicks, link.Exhausted())
+	}
+	for range 5 {
+		if ok, _ := s.ClaimClick(ctx, 2); !ok {
+			t.Error("click on unlimited link refused")
+		}
+	}
+	if ok, _ := s.ClaimClick(ctx, 3); ok {
+		t.Error("click on missing link accepted")
+	}
+}
+
+func TestMemoryStoreDeleteLink(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	if err := s.SaveLink(ctx, &Link{ID: 1, Alias: "gone", URLHash: "h"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteLink(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteLink(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second DeleteLink = %v, want ErrNotFound", err)
+	}
+	// 别名与长链接哈希随链接一起释放
+	if err := s.SaveLink(ctx, &Link{ID: 2, Alias: "gone", URLHash: "h"}); err != nil {
+		t.Errorf("reusing alias and hash after delete: %v", err)
+	}
+}
+
+func TestMemoryStoreListLinks(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	var links []*Link
+	for id := int64(1); id <= 7; id++ {
+		owner := "alice"
+		if id%2 == 0 {
+			owner = "bob"
+		}
+		links = append(links, &Link{ID: id, OwnerID: owner})
+	}
+	if err := s.SaveLinks(ctx, links); err != nil {
+		t.Fatal(err)
+	}
+
+	alice, bob := "alice", "bob"
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []int64
+	}{
+		{"first page", ListOptions{Limit: 3}, []int64{1, 2, 3}},
+		{"next page", ListOptions{AfterID: 3, Limit: 3}, []int64{4, 5, 6}},
+		{"last page", ListOptions{AfterID: 6, Limit: 3}, []int64{7}},
+		{"past the end", ListOptions{AfterID: 7, Limit: 3}, []int64{}},
+		{"owner", ListOptions{OwnerID: &alice, Limit: 10}, []int64{1, 3, 5, 7}},
+		{"owner after cursor", ListOptions{OwnerID: &bob, AfterID: 2, Limit: 1}, []int64{4}},
+	}
+	for _, tt := range tests {
+		got, err := s.ListLinks(ctx, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]int64, len(got))
+		for i, l := range got {
+			ids[i] = l.ID
+		}
+		if len(ids) != len(tt.want) {
+			t.Errorf("%s: ListLinks = %v, want %v", tt.name, ids, tt.want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.want[i] {
+				t.Errorf("%s: ListLinks = %v, want %v", tt.name, ids, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestMemoryStoreDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	links := []*Link{
+		{ID: 1, ExpiresAt: at(now.Add(-2 * time.Hour)), Alias: "old"},
+		{ID: 2, ExpiresAt: at(now.Add(-time.Hour))},
+		{ID: 3, ExpiresAt: at(now.Add(-time.Minute))},
+		{ID: 4, ExpiresAt: at(now.Add(time.Hour))},
+		{ID: 5},
+	}
+	if err := s.SaveLinks(ctx, links); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := s.DeleteExpired(ctx, now, 2); err != nil || n != 2 {
+		t.Errorf("DeleteExpired(limit 2) = %d, %v; want 2", n, err)
+	}
+	if n, err := s.DeleteExpired(ctx, now, 10); err != nil || n != 1 {
+		t.Errorf("second DeleteExpired = %d, %v; want 1", n, err)
+	}
+	for id, want := range map[int64]bool{1: false, 2: false, 3: false, 4: true, 5: true} {
+		if _, err := s.GetLink(ctx, id); (err == nil) != want {
+			t.Errorf("link %d present = %v, want %v", id, err == nil, want)
+		}
+	}
+	if _, err := s.GetLinkByAlias(ctx, "", "old"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("alias of expired link still indexed: %v", err)
+	}
+}
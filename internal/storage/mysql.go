@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// NewMySQLStore 使用已打开的 MySQL 连接创建 SQLStore
+func NewMySQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, d: mysqlDialect{}}
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) bind(query string) string {
+	return query
+}
+
+// isUniqueConflict 错误信息形如 Duplicate entry '...' for key 'urls.uk_alias'
+func (mysqlDialect) isUniqueConflict(err error, key string) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 && // ER_DUP_ENTRY
+		strings.HasSuffix(mysqlErr.Message, key+"'")
+}
+
+func (mysqlDialect) insertID(ctx context.Context, db *sql.DB, query string, args ...any) (int64, error) {
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (mysqlDialect) deleteExpired(ctx context.Context, db *sql.DB, before time.Time, limit int) (int64, error) {
+	res, err := db.ExecContext(ctx, "DELETE FROM urls WHERE expires_at IS NOT NULL AND expires_at < ? LIMIT ?", before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (mysqlDialect) nextID(ctx context.Context, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, "INSERT INTO tickets (stub) VALUES ('a')")
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (mysqlDialect) initSegment(ctx context.Context, db *sql.DB, tag string) error {
+	_, err := db.ExecContext(ctx, `
+	INSERT IGNORE INTO id_segments (biz_tag, max_id)
+	SELECT ?, GREATEST(
+		(SELECT COALESCE(MAX(id), 0) FROM tickets),
+		(SELECT COALESCE(MAX(id), 0) FROM urls))`, tag)
+	return err
+}
+
+// fetchSegment 利用 LAST_INSERT_ID(expr) 在一条 UPDATE 中完成推进和读取
+func (mysqlDialect) fetchSegment(ctx context.Context, db *sql.DB, tag string, step int64) (int64, error) {
+	res, err := db.ExecContext(ctx, "UPDATE id_segments SET max_id = LAST_INSERT_ID(max_id + ?) WHERE biz_tag = ?", step, tag)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, fmt.Errorf("id segment %q not initialized", tag)
+	}
+	return res.LastInsertId()
+}
+
+func (mysqlDialect) initUsage(ctx context.Context, db *sql.DB, owner, period string) error {
+	_, err := db.ExecContext(ctx, "INSERT IGNORE INTO link_usage (owner_id, period) VALUES (?, ?)", owner, period)
+	return err
+}
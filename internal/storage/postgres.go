@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yin1895/tinylink/internal/sqlbind"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib" // 注册 pgx 驱动
+)
+
+// NewPostgresStore 使用已打开的 PostgreSQL 连接创建 SQLStore
+func NewPostgresStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, d: postgresDialect{}}
+}
+
+type postgresDialect struct{}
+
+// bind 将 ? 依次替换为 $1, $2, ...
+func (postgresDialect) bind(query string) string {
+	return sqlbind.Dollar(query)
+}
+
+func (postgresDialect) isUniqueConflict(err error, key string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && // unique_violation
+		pgErr.ConstraintName == key
+}
+
+// insertID PostgreSQL 驱动不支持 LastInsertId，改用 RETURNING
+func (postgresDialect) insertID(ctx context.Context, db *sql.DB, query string, args ...any) (int64, error) {
+	var id int64
+	err := db.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+	return id, err
+}
+
+// deleteExpired PostgreSQL 的 DELETE 不支持 LIMIT，借助子查询分批删除
+func (postgresDialect) deleteExpired(ctx context.Context, db *sql.DB, before time.Time, limit int) (int64, error) {
+	res, err := db.ExecContext(ctx, `
+	DELETE FROM urls WHERE id IN (
+		SELECT id FROM urls WHERE expires_at IS NOT NULL AND expires_at < $1 LIMIT $2)`, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (postgresDialect) nextID(ctx context.Context, db *sql.DB) (int64, error) {
+	var id int64
+	err := db.QueryRowContext(ctx, "SELECT nextval('tickets_id_seq')").Scan(&id)
+	return id, err
+}
+
+func (postgresDialect) initSegment(ctx context.Context, db *sql.DB, tag string) error {
+	_, err := db.ExecContext(ctx, `
+	INSERT INTO id_segments (biz_tag, max_id)
+	SELECT $1, GREATEST(
+		(SELECT CASE WHEN is_called THEN last_value ELSE 0 END FROM tickets_id_seq),
+		(SELECT COALESCE(MAX(id), 0) FROM urls))
+	ON CONFLICT (biz_tag) DO NOTHING`, tag)
+	return err
+}
+
+// fetchSegment 利用 UPDATE ... RETURNING 在一条语句中完成推进和读取
+func (postgresDialect) fetchSegment(ctx context.Context, db *sql.DB, tag string, step int64) (int64, error) {
+	var maxID int64
+	err := db.QueryRowContext(ctx,
+		"UPDATE id_segments SET max_id = max_id + $1, updated_at = NOW() WHERE biz_tag = $2 RETURNING max_id",
+		step, tag).Scan(&maxID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("id segment %q not initialized", tag)
+	}
+	return maxID, err
+}
+
+func (postgresDialect) initUsage(ctx context.Context, db *sql.DB, owner, period string) error {
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO link_usage (owner_id, period) VALUES ($1, $2) ON CONFLICT (owner_id, period) DO NOTHING", owner, period)
+	return err
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RateLimit 一次限流判定的结果
+type RateLimit struct {
+	Allowed    bool
+	Remaining  int           // 当前窗口内剩余可用次数
+	RetryAfter time.Duration // 被拒绝时需等待的时间
+}
+
+// RateLimiter 滑动窗口限流器：任意 window 时长内同一 key 最多放行 limit 次
+type RateLimiter interface {
+	// Allow 判定 key 的本次请求，放行时计入窗口
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (RateLimit, error)
+}
+
+// slidingWindowScript 以有序集合记录窗口内每次放行的时间 (毫秒)，判定与写入在一个脚本内原子完成
+// 返回 {是否放行, 剩余次数, 需等待的毫秒数}
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, limit - count - 1, 0}
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {0, 0, tonumber(oldest[2]) + window - now}
+`)
+
+// RedisRateLimiter 基于 Redis 的滑动窗口限流，多个 API 实例共享计数
+type RedisRateLimiter struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+// NewRedisRateLimiter 创建限流器，计数保存在 prefix + key 下
+func NewRedisRateLimiter(rdb *redis.Client, prefix string) *RedisRateLimiter {
+	return &RedisRateLimiter{rdb: rdb, prefix: prefix}
+}
+
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (RateLimit, error) {
+	now := time.Now()
+	// 同一毫秒内可能有多个请求，成员名附加随机数避免相互覆盖
+	member := strconv.FormatInt(now.UnixNano(), 10) + "-" + strconv.FormatUint(rand.Uint64(), 36)
+	res, err := slidingWindowScript.Run(ctx, l.rdb, []string{l.prefix + key},
+		now.UnixMilli(), window.Milliseconds(), limit, member).Int64Slice()
+	if err != nil {
+		return RateLimit{}, err
+	}
+	return RateLimit{
+		Allowed:    res[0] == 1,
+		Remaining:  int(res[1]),
+		RetryAfter: time.Duration(res[2]) * time.Millisecond,
+	}, nil
+}
+
+// MemoryRateLimiter 进程内滑动窗口限流，供单机模式使用
+// 每隔 memorySweepEvery 次判定清理一次已空闲的 key
+type MemoryRateLimiter struct {
+	mu     sync.Mutex
+	keys   map[string]*memoryWindow
+	checks int
+	now    func() time.Time
+}
+
+// memoryWindow 一个 key 的窗口时长及窗口内放行的时间 (按先后排列)
+// 不同策略的窗口长度不同，清理时按各 key 自己的窗口判断是否空闲
+type memoryWindow struct {
+	window time.Duration
+	hits   []time.Time
+}
+
+// NewMemoryRateLimiter 创建进程内限流器
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{keys: make(map[string]*memoryWindow), now: time.Now}
+}
+
+func (l *MemoryRateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (RateLimit, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+
+	l.checks++
+	if l.checks%memorySweepEvery == 0 {
+		l.sweep(now)
+	}
+
+	w, ok := l.keys[key]
+	if !ok {
+		w = &memoryWindow{}
+		l.keys[key] = w
+	}
+	w.window = window
+	i := 0
+	for i < len(w.hits) && !now.Before(w.hits[i].Add(window)) {
+		i++
+	}
+	w.hits = w.hits[i:]
+	if len(w.hits) >= limit {
+		return RateLimit{RetryAfter: w.hits[0].Add(window).Sub(now)}, nil
+	}
+	w.hits = append(w.hits, now)
+	return RateLimit{Allowed: true, Remaining: limit - len(w.hits)}, nil
+}
+
+// sweep 删除最后一次放行已超出自身窗口的 key，调用方持有锁
+func (l *MemoryRateLimiter) sweep(now time.Time) {
+	for k, w := range l.keys {
+		if len(w.hits) == 0 || !now.Before(w.hits[len(w.hits)-1].Add(w.window)) {
+			delete(l.keys, k)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeClock 供测试控制 MemoryRateLimiter 的当前时间
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestRateLimiter() (*MemoryRateLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewMemoryRateLimiter()
+	l.now = clock.now
+	return l, clock
+}
+
+func TestMemoryRateLimiterSlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	l, clock := newTestRateLimiter()
+	const window = time.Minute
+
+	steps := []struct {
+		advance    time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{0, true, 2, 0},
+		{10 * time.Second, true, 1, 0},
+		{10 * time.Second, true, 0, 0},
+		{10 * time.Second, false, 0, 30 * time.Second}, // 第一次放行在 60s 时移出窗口
+		{30 * time.Second, true, 0, 0},                 // t=60s，第一次放行已移出
+		{time.Second, false, 0, 9 * time.Second},       // t=61s，第二次放行在 70s 移出
+		{2 * time.Minute, true, 2, 0},                  // 窗口内已没有放行记录
+	}
+	for i, st := range steps {
+		clock.advance(st.advance)
+		got, err := l.Allow(ctx, "ip:1", 3, window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := RateLimit{Allowed: st.allowed, Remaining: st.remaining, RetryAfter: st.retryAfter}
+		if got != want {
+			t.Errorf("step %d: Allow = %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestMemoryRateLimiterKeysAreIndependent(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestRateLimiter()
+	if rl, _ := l.Allow(ctx, "a", 1, time.Minute); !rl.Allowed {
+		t.Fatal("first request for a denied")
+	}
+	if rl, _ := l.Allow(ctx, "a", 1, time.Minute); rl.Allowed {
+		t.Error("second request for a allowed")
+	}
+	if rl, _ := l.Allow(ctx, "b", 1, time.Minute); !rl.Allowed {
+		t.Error("first request for b denied")
+	}
+}
+
+// TestMemoryRateLimiterSweepMixedWindows 短窗口策略触发的清理不应删除长窗口策略仍在计数的 key
+func TestMemoryRateLimiterSweepMixedWindows(t *testing.T) {
+	ctx := context.Background()
+	l, clock := newTestRateLimiter()
+	const (
+		shortWindow = time.Minute
+		longWindow  = 15 * time.Minute
+	)
+
+	for range 5 {
+		if rl, _ := l.Allow(ctx, "password:abc", 5, longWindow); !rl.Allowed {
+			t.Fatal("password attempt denied before reaching the limit")
+		}
+	}
+	if rl, _ := l.Allow(ctx, "password:abc", 5, longWindow); rl.Allowed {
+		t.Fatal("sixth password attempt allowed")
+	}
+
+	// 两分钟后大量短窗口的请求触发清理，此时 60s 的窗口早已过去，但 900s 的窗口还没有
+	clock.advance(2 * time.Minute)
+	for i := range memorySweepEvery {
+		l.Allow(ctx, "ip:"+strconv.Itoa(i), 100, shortWindow)
+	}
+
+	rl, _ := l.Allow(ctx, "password:abc", 5, longWindow)
+	if rl.Allowed {
+		t.Fatal("password attempts were forgotten by a sweep using another policy's window")
+	}
+	if want := longWindow - 2*time.Minute; rl.RetryAfter != want {
+		t.Errorf("RetryAfter = %v, want %v", rl.RetryAfter, want)
+	}
+}
+
+func TestMemoryRateLimiterSweepRemovesIdleKeys(t *testing.T) {
+	ctx := context.Background()
+	l, clock := newTestRateLimiter()
+	l.Allow(ctx, "short", 10, time.Minute)
+	l.Allow(ctx, "long", 10, 15*time.Minute)
+
+	clock.advance(5 * time.Minute)
+	l.sweep(clock.now())
+	if _, ok := l.keys["short"]; ok {
+		t.Error("idle key with a 1m window was not swept after 5m")
+	}
+	if _, ok := l.keys["long"]; !ok {
+		t.Error("key with a 15m window was swept after 5m")
+	}
+
+	clock.advance(10 * time.Minute)
+	l.sweep(clock.now())
+	if len(l.keys) != 0 {
+		t.Errorf("%d keys left after all windows passed", len(l.keys))
+	}
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// reapBatchSize 每条 DELETE 语句最多删除的行数，避免长时间锁表
+const reapBatchSize = 1000
+
+// StartReaper 启动后台清理协程，每隔 interval 从 store 中删除过期超过 grace 的链接
+// 保留 grace 宽限期是为了让刚过期的链接在这段时间内仍返回 410 而非 404
+// ctx 取消后协程退出
+func StartReaper(ctx context.Context, store LinkStore, interval, grace time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reapExpired(ctx, store, time.Now().Add(-grace))
+			}
+		}
+	}()
+}
+
+func reapExpired(ctx context.Context, store LinkStore, before time.Time) {
+	var total int64
+	for {
+		n, err := store.DeleteExpired(ctx, before, reapBatchSize)
+		if err != nil {
+			log.Printf("Failed to purge expired links: %v", err)
+			return
+		}
+		total += n
+		if n < reapBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("Purged %d expired links", total)
+	}
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestReapExpired 分批删除过期超过宽限期的链接，直到某一批不足 reapBatchSize
+func TestReapExpired(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	grace := 24 * time.Hour
+
+	var links []*Link
+	for i := range reapBatchSize + 10 {
+		links = append(links, &Link{ID: int64(i + 1), ExpiresAt: at(now.Add(-grace - time.Minute))})
+	}
+	links = append(links,
+		&Link{ID: 10001, ExpiresAt: at(now.Add(-time.Hour))}, // 已过期但仍在宽限期内
+		&Link{ID: 10002, ExpiresAt: at(now.Add(time.Hour))},
+		&Link{ID: 10003},
+	)
+	if err := s.SaveLinks(ctx, links); err != nil {
+		t.Fatal(err)
+	}
+
+	reapExpired(ctx, s, now.Add(-grace))
+	if _, err := s.GetLink(ctx, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("link expired before the grace period is still present: %v", err)
+	}
+	left, err := s.ListLinks(ctx, ListOptions{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 3 || left[0].ID != 10001 {
+		t.Errorf("%d links left after reaping, want the 3 within their grace period or not expired", len(left))
+	}
+}
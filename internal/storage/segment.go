@@ -0,0 +1,18 @@
+package storage
+
+import "context"
+
+// SegmentTagURLs 短链接 ID 使用的号段标识
+const SegmentTagURLs = "urls"
+
+// InitSegment 确保号段记录存在
+// 首次创建时从 tickets 和 urls 已发放的最大 ID 之后开始，避免与旧的逐条发号方式冲突
+func (s *SQLStore) InitSegment(ctx context.Context, tag string) error {
+	return s.d.initSegment(ctx, s.db, tag)
+}
+
+// FetchSegment 原子地将号段上界推进 step，返回新的上界 maxID
+// 调用方获得 (maxID-step, maxID] 区间内的 ID，只需一次数据库往返
+func (s *SQLStore) FetchSegment(ctx context.Context, tag string, step int64) (int64, error) {
+	return s.d.fetchSegment(ctx, s.db, tag, step)
+}
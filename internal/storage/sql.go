@@ -0,0 +1,305 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dialect 封装不同 SQL 数据库之间的差异 (建表语句、占位符、错误码、发号方式)
+type dialect interface {
+	// bind 将查询中的 ? 占位符转换为方言格式
+	bind(query string) string
+	// isUniqueConflict 判断错误是否为指定唯一键 (uk_alias / uk_url_hash) 冲突
+	isUniqueConflict(err error, key string) bool
+	// insertID 执行单行 INSERT 并返回自增 ID
+	insertID(ctx context.Context, db *sql.DB, query string, args ...any) (int64, error)
+	// deleteExpired 删除在 before 之前过期的链接，最多 limit 行
+	deleteExpired(ctx context.Context, db *sql.DB, before time.Time, limit int) (int64, error)
+	// nextID 通过 tickets 获取下一个全局唯一 ID
+	nextID(ctx context.Context, db *sql.DB) (int64, error)
+	// initSegment 确保号段记录存在
+	initSegment(ctx context.Context, db *sql.DB, tag string) error
+	// fetchSegment 原子地将号段上界推进 step，返回新的上界
+	fetchSegment(ctx context.Context, db *sql.DB, tag string, step int64) (int64, error)
+	// initUsage 确保持有者在统计周期内的用量记录存在
+	initUsage(ctx context.Context, db *sql.DB, owner, period string) error
+}
+
+// SQLStore 基于关系型数据库的 LinkStore 实现，通过 dialect 支持 MySQL 和 PostgreSQL
+type SQLStore struct {
+	db *sql.DB
+	d  dialect
+}
+
+// Close 关闭数据库连接
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// linkColumns 与 scanLink 的扫描顺序一致
+const linkColumns = "id, long_url, targets, geo_targets, variants, query_params, pass_query, domain, owner_id, alias, url_hash, password_hash, max_clicks, clicks, not_before, not_after, expires_at, created_at"
+
+// SaveLink 保存链接，别名冲突时返回 ErrAliasTaken，长链接哈希冲突时返回 ErrURLExists
+func (s *SQLStore) SaveLink(ctx context.Context, link *Link) error {
+	return s.SaveLinks(ctx, []*Link{link})
+}
+
+// SaveLinks 使用一条多行 INSERT 批量保存链接
+func (s *SQLStore) SaveLinks(ctx context.Context, links []*Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+	placeholders := make([]string, len(links))
+	args := make([]any, 0, len(links)*16)
+	for i, link := range links {
+		placeholders[i] = "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+		args = append(args, link.ID, link.LongURL, targetsColumn(link.Targets), targetsColumn(link.GeoTargets),
+			variantsColumn(link.Variants), targetsColumn(link.QueryParams), link.PassQuery, link.Domain, link.OwnerID, nullString(link.Alias), nullString(link.URLHash),
+			nullString(link.PasswordHash), link.MaxClicks, link.NotBefore, link.NotAfter, link.ExpiresAt)
+	}
+	_, err := s.db.ExecContext(ctx, s.d.bind("INSERT INTO urls(id, long_url, targets, geo_targets, variants, query_params, pass_query, domain, owner_id, alias, url_hash, password_hash, max_clicks, not_before, not_after, expires_at) VALUES "+
+		strings.Join(placeholders, ", ")), args...)
+	switch {
+	case err == nil:
+		return nil
+	case s.d.isUniqueConflict(err, "uk_alias"):
+		return ErrAliasTaken
+	case s.d.isUniqueConflict(err, "uk_url_hash"):
+		return ErrURLExists
+	}
+	return err
+}
+
+// GetLink 根据 ID 获取链接
+func (s *SQLStore) GetLink(ctx context.Context, id int64) (*Link, error) {
+	return scanLink(s.db.QueryRowContext(ctx, s.d.bind("SELECT "+linkColumns+" FROM urls WHERE id = ?"), id))
+}
+
+// GetLinkByAlias 根据域名和自定义别名获取链接
+func (s *SQLStore) GetLinkByAlias(ctx context.Context, domain, alias string) (*Link, error) {
+	return scanLink(s.db.QueryRowContext(ctx, s.d.bind("SELECT "+linkColumns+" FROM urls WHERE domain = ? AND alias = ?"),
+		domain, alias))
+}
+
+// FindAliases 用一条 IN 查询返回 aliases 中在域名内已被使用的别名
+func (s *SQLStore) FindAliases(ctx context.Context, domain string, aliases []string) ([]string, error) {
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+	args := make([]any, 0, len(aliases)+1)
+	args = append(args, domain)
+	for _, alias := range aliases {
+		args = append(args, alias)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(aliases)), ", ")
+	rows, err := s.db.QueryContext(ctx, s.d.bind("SELECT alias FROM urls WHERE domain = ? AND alias IN ("+placeholders+")"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found []string
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			return nil, err
+		}
+		found = append(found, alias)
+	}
+	return found, rows.Err()
+}
+
+// GetLinkByURLHash 根据域名、创建者和长链接哈希获取去重链接
+func (s *SQLStore) GetLinkByURLHash(ctx context.Context, domain, ownerID, hash string) (*Link, error) {
+	return scanLink(s.db.QueryRowContext(ctx,
+		s.d.bind("SELECT "+linkColumns+" FROM urls WHERE domain = ? AND owner_id = ? AND url_hash = ?"),
+		domain, ownerID, hash))
+}
+
+// UpdateLink 更新链接中可通过管理接口修改的字段 (目标地址与跳转规则、访问限制、生效时间和过期时间) 及长链接哈希，
+// 链接不存在 (如已被并发删除) 时返回 ErrNotFound
+func (s *SQLStore) UpdateLink(ctx context.Context, link *Link) error {
+	res, err := s.db.ExecContext(ctx,
+		s.d.bind("UPDATE urls SET long_url = ?, targets = ?, geo_targets = ?, variants = ?, query_params = ?, pass_query = ?, url_hash = ?, password_hash = ?, max_clicks = ?, not_before = ?, not_after = ?, expires_at = ? WHERE id = ?"),
+		link.LongURL, targetsColumn(link.Targets), targetsColumn(link.GeoTargets), variantsColumn(link.Variants),
+		targetsColumn(link.QueryParams), link.PassQuery, nullString(link.URLHash), nullString(link.PasswordHash), link.MaxClicks,
+		link.NotBefore, link.NotAfter, link.ExpiresAt, link.ID)
+	if err != nil {
+		if s.d.isUniqueConflict(err, "uk_url_hash") {
+			return ErrURLExists
+		}
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n > 0 {
+		return err
+	}
+	// MySQL 对值未发生变化的行同样返回 0，需确认链接是否仍然存在
+	var exists int
+	err = s.db.QueryRowContext(ctx, s.d.bind("SELECT 1 FROM urls WHERE id = ?"), link.ID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ClaimClick 用带条件的 UPDATE 原子地计入一次访问
+func (s *SQLStore) ClaimClick(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		s.d.bind("UPDATE urls SET clicks = clicks + 1 WHERE id = ? AND (max_clicks = 0 OR clicks < max_clicks)"), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// DeleteLink 删除链接，不存在时返回 ErrNotFound
+func (s *SQLStore) DeleteLink(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, s.d.bind("DELETE FROM urls WHERE id = ?"), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListLinks 按 ID 升序分页列出链接
+func (s *SQLStore) ListLinks(ctx context.Context, opts ListOptions) ([]*Link, error) {
+	query, args := "SELECT "+linkColumns+" FROM urls WHERE id > ?", []any{opts.AfterID}
+	if opts.OwnerID != nil {
+		query += " AND owner_id = ?"
+		args = append(args, *opts.OwnerID)
+	}
+	rows, err := s.db.QueryContext(ctx, s.d.bind(query+" ORDER BY id LIMIT ?"), append(args, opts.Limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// DeleteExpired 删除在 before 之前过期的链接，每次最多 limit 行
+func (s *SQLStore) DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return s.d.deleteExpired(ctx, s.db, before, limit)
+}
+
+// GetNextID 获取下一个全局唯一 ID
+func (s *SQLStore) GetNextID(ctx context.Context) (int64, error) {
+	return s.d.nextID(ctx, s.db)
+}
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLink(row rowScanner) (*Link, error) {
+	var (
+		link      Link
+		targets   sql.NullString
+		geo       sql.NullString
+		variants  sql.NullString
+		query     sql.NullString
+		passQuery sql.NullBool
+		alias     sql.NullString
+		urlHash   sql.NullString
+		password  sql.NullString
+		notBefore sql.NullTime
+		notAfter  sql.NullTime
+		expiresAt sql.NullTime
+	)
+	err := row.Scan(&link.ID, &link.LongURL, &targets, &geo, &variants, &query, &passQuery,
+		&link.Domain, &link.OwnerID, &alias, &urlHash, &password,
+		&link.MaxClicks, &link.Clicks, &notBefore, &notAfter, &expiresAt, &link.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := scanTargets(targets, &link.Targets); err != nil {
+		return nil, fmt.Errorf("link %d: invalid targets: %w", link.ID, err)
+	}
+	if err := scanTargets(geo, &link.GeoTargets); err != nil {
+		return nil, fmt.Errorf("link %d: invalid geo_targets: %w", link.ID, err)
+	}
+	if err := scanTargets(query, &link.QueryParams); err != nil {
+		return nil, fmt.Errorf("link %d: invalid query_params: %w", link.ID, err)
+	}
+	if passQuery.Valid {
+		link.PassQuery = &passQuery.Bool
+	}
+	if variants.Valid {
+		if err := json.Unmarshal([]byte(variants.String), &link.Variants); err != nil {
+			return nil, fmt.Errorf("link %d: invalid variants: %w", link.ID, err)
+		}
+	}
+	link.Alias = alias.String
+	link.URLHash = urlHash.String
+	link.PasswordHash = password.String
+	link.NotBefore = nullTime(notBefore)
+	link.NotAfter = nullTime(notAfter)
+	link.ExpiresAt = nullTime(expiresAt)
+	link.CreatedAt = link.CreatedAt.UTC()
+	return &link, nil
+}
+
+// targetsColumn 将字符串映射 (设备平台或国家 -> 目标地址、查询参数) 编码为 JSON，为空时写入 NULL
+func targetsColumn(targets map[string]string) sql.NullString {
+	if len(targets) == 0 {
+		return sql.NullString{}
+	}
+	b, _ := json.Marshal(targets)
+	return sql.NullString{String: string(b), Valid: true}
+}
+
+// scanTargets 解码 targetsColumn 写入的 JSON，NULL 时保持 nil
+func scanTargets(col sql.NullString, targets *map[string]string) error {
+	if !col.Valid {
+		return nil
+	}
+	return json.Unmarshal([]byte(col.String), targets)
+}
+
+// variantsColumn 将 A/B 分组编码为 JSON，为空时写入 NULL
+func variantsColumn(variants []Variant) sql.NullString {
+	if len(variants) == 0 {
+		return sql.NullString{}
+	}
+	b, _ := json.Marshal(variants)
+	return sql.NullString{String: string(b), Valid: true}
+}
+
+// nullTime 将可空时间转为 UTC 指针，NULL 返回 nil
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	u := t.Time.UTC()
+	return &u
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// stubConn 只支持 UpdateLink 用到的两条语句的数据库连接：
+// UPDATE 返回 updated 行，SELECT 在 exists 为 true 时返回一行
+type stubConn struct {
+	updated int64
+	exists  bool
+	queries []string
+}
+
+func (c *stubConn) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c *stubConn) Driver() driver.Driver                        { return nil }
+func (c *stubConn) Prepare(query string) (driver.Stmt, error)    { return &stubStmt{c, query}, nil }
+func (c *stubConn) Close() error                                 { return nil }
+func (c *stubConn) Begin() (driver.Tx, error)                    { return nil, errors.New("not supported") }
+
+type stubStmt struct {
+	c     *stubConn
+	query string
+}
+
+func (s *stubStmt) Close() error  { return nil }
+func (s *stubStmt) NumInput() int { return -1 }
+
+func (s *stubStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.c.queries = append(s.c.queries, s.query)
+	return driver.RowsAffected(s.c.updated), nil
+}
+
+func (s *stubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.c.queries = append(s.c.queries, s.query)
+	return &stubRows{left: s.c.exists}, nil
+}
+
+type stubRows struct{ left bool }
+
+func (r *stubRows) Columns() []string { return []string{"1"} }
+func (r *stubRows) Close() error      { return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if !r.left {
+		return io.EOF
+	}
+	r.left = false
+	dest[0] = int64(1)
+	return nil
+}
+
+// TestSQLStoreUpdateLinkMissing 没有行被更新时区分链接已删除与值未变化 (MySQL 对未变化的行返回 0)
+func TestSQLStoreUpdateLinkMissing(t *testing.T) {
+	tests := []struct {
+		name    string
+		updated int64
+		exists  bool
+		want    error
+		queries int
+	}{
+		{"updated", 1, true, nil, 1},
+		{"unchanged", 0, true, nil, 2},
+		{"deleted concurrently", 0, false, ErrNotFound, 2},
+	}
+	for _, tt := range tests {
+		conn := &stubConn{updated: tt.updated, exists: tt.exists}
+		db := sql.OpenDB(conn)
+		for _, s := range []*SQLStore{NewMySQLStore(db), NewPostgresStore(db)} {
+			conn.queries = nil
+			err := s.UpdateLink(context.Background(), &Link{ID: 1, LongURL: "https://example.com/"})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("%s: UpdateLink = %v, want %v", tt.name, err, tt.want)
+			}
+			if len(conn.queries) != tt.queries || !strings.HasPrefix(conn.queries[0], "UPDATE urls") {
+				t.Errorf("%s: executed %q", tt.name, conn.queries)
+			}
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// UsagePeriod 返回 t 所在的配额统计周期 (UTC 自然月)，形如 2026-01
+func UsagePeriod(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// UsageStore 按持有者统计链接用量，供配额使用
+type UsageStore interface {
+	// ReserveLinks 原子地为持有者在 period 内计入 n 个新链接
+	// limit 大于 0 且计入后会超过 limit 时不计入，返回 false
+	ReserveLinks(ctx context.Context, owner, period string, n, limit int64) (bool, error)
+	// ReleaseLinks 退还 ReserveLinks 计入的 n 个名额 (链接最终未保存时)
+	ReleaseLinks(ctx context.Context, owner, period string, n int64) error
+	// LinksCreated 返回持有者在 period 内新建的链接数
+	LinksCreated(ctx context.Context, owner, period string) (int64, error)
+	// CountActiveLinks 返回持有者在 now 时刻未过期的链接数
+	CountActiveLinks(ctx context.Context, owner string, now time.Time) (int64, error)
+}
+
+// ReserveLinks 先确保计数行存在，再用带条件的 UPDATE 原子地检查并累加
+func (s *SQLStore) ReserveLinks(ctx context.Context, owner, period string, n, limit int64) (bool, error) {
+	if err := s.d.initUsage(ctx, s.db, owner, period); err != nil {
+		return false, err
+	}
+	res, err := s.db.ExecContext(ctx, s.d.bind(
+		"UPDATE link_usage SET links_created = links_created + ? WHERE owner_id = ? AND period = ? AND (? = 0 OR links_created + ? <= ?)"),
+		n, owner, period, limit, n, limit)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected == 1, err
+}
+
+// ReleaseLinks 退还名额
+func (s *SQLStore) ReleaseLinks(ctx context.Context, owner, period string, n int64) error {
+	_, err := s.db.ExecContext(ctx, s.d.bind(
+		"UPDATE link_usage SET links_created = GREATEST(links_created - ?, 0) WHERE owner_id = ? AND period = ?"),
+		n, owner, period)
+	return err
+}
+
+// LinksCreated 返回持有者在 period 内新建的链接数
+func (s *SQLStore) LinksCreated(ctx context.Context, owner, period string) (int64, error) {
+	var n int64
+	err := s.db.QueryRowContext(ctx, s.d.bind("SELECT links_created FROM link_usage WHERE owner_id = ? AND period = ?"),
+		owner, period).Scan(&n)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// CountActiveLinks 返回持有者未过期的链接数
+func (s *SQLStore) CountActiveLinks(ctx context.Context, owner string, now time.Time) (int64, error) {
+	var n int64
+	err := s.db.QueryRowContext(ctx, s.d.bind(
+		"SELECT COUNT(*) FROM urls WHERE owner_id = ? AND (expires_at IS NULL OR expires_at > ?)"),
+		owner, now.UTC()).Scan(&n)
+	return n, err
+}
+
+// usageKey 持有者在某个统计周期的用量
+type usageKey struct {
+	owner, period string
+}
+
+// ReserveLinks 为持有者计入 n 个新链接，超过 limit 时不计入
+func (s *MemoryStore) ReserveLinks(ctx context.Context, owner, period string, n, limit int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := usageKey{owner, period}
+	if limit > 0 && s.usage[k]+n > limit {
+		return false, nil
+	}
+	s.usage[k] += n
+	return true, nil
+}
+
+// ReleaseLinks 退还名额
+func (s *MemoryStore) ReleaseLinks(ctx context.Context, owner, period string, n int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := usageKey{owner, period}
+	s.usage[k] = max(s.usage[k]-n, 0)
+	return nil
+}
+
+// LinksCreated 返回持有者在 period 内新建的链接数
+func (s *MemoryStore) LinksCreated(ctx context.Context, owner, period string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.usage[usageKey{owner, period}], nil
+}
+
+// CountActiveLinks 返回持有者未过期的链接数
+func (s *MemoryStore) CountActiveLinks(ctx context.Context, owner string, now time.Time) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var n int64
+	for _, link := range s.links {
+		if link.OwnerID == owner && !link.Expired(now) {
+			n++
+		}
+	}
+	return n, nil
+}
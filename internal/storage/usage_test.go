@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUsagePeriod(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	if got := UsagePeriod(time.Date(2026, 3, 1, 7, 0, 0, 0, loc)); got != "2026-02" {
+		t.Errorf("UsagePeriod = %q, want 2026-02 (periods follow UTC)", got)
+	}
+}
+
+func TestMemoryStoreUsage(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	for i, want := range []bool{true, true, false} {
+		if ok, err := s.ReserveLinks(ctx, "alice", "2026-03", 2, 5); err != nil || ok != want {
+			t.Errorf("reserve %d: %v, %v; want %v", i+1, ok, err, want)
+		}
+	}
+	if n, _ := s.LinksCreated(ctx, "alice", "2026-03"); n != 4 {
+		t.Errorf("LinksCreated = %d, want 4 (a refused reservation is not counted)", n)
+	}
+	if ok, _ := s.ReserveLinks(ctx, "alice", "2026-04", 5, 5); !ok {
+		t.Error("new period starts from zero")
+	}
+	if ok, _ := s.ReserveLinks(ctx, "bob", "2026-03", 100, 0); !ok {
+		t.Error("limit 0 is unlimited")
+	}
+
+	if err := s.ReleaseLinks(ctx, "alice", "2026-03", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReleaseLinks(ctx, "alice", "2026-03", 3); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := s.LinksCreated(ctx, "alice", "2026-03"); n != 0 {
+		t.Errorf("LinksCreated after release = %d, want 0 (never negative)", n)
+	}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := s.SaveLinks(ctx, []*Link{
+		{ID: 1, OwnerID: "alice"},
+		{ID: 2, OwnerID: "alice", ExpiresAt: at(now.Add(time.Hour))},
+		{ID: 3, OwnerID: "alice", ExpiresAt: at(now)},
+		{ID: 4, OwnerID: "bob"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := s.CountActiveLinks(ctx, "alice", now); n != 2 {
+		t.Errorf("CountActiveLinks(alice) = %d, want 2", n)
+	}
+}
@@ -103,6 +103,104 @@ func (x *GenerateIdResponse) GetId() int64 {
 	return 0
 }
 
+// 申请连续号段的请求消息
+type GenerateIdRangeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateIdRangeRequest) Reset() {
+	*x = GenerateIdRangeRequest{}
+	mi := &file_pkg_proto_tinylink_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateIdRangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateIdRangeRequest) ProtoMessage() {}
+
+func (x *GenerateIdRangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_tinylink_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateIdRangeRequest.ProtoReflect.Descriptor instead.
+func (*GenerateIdRangeRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_tinylink_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GenerateIdRangeRequest) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+// 连续号段的响应消息，包含 [start, start+count) 区间内的 ID
+type GenerateIdRangeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         int64                  `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateIdRangeResponse) Reset() {
+	*x = GenerateIdRangeResponse{}
+	mi := &file_pkg_proto_tinylink_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateIdRangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateIdRangeResponse) ProtoMessage() {}
+
+func (x *GenerateIdRangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_tinylink_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateIdRangeResponse.ProtoReflect.Descriptor instead.
+func (*GenerateIdRangeResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_tinylink_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GenerateIdRangeResponse) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *GenerateIdRangeResponse) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
 var File_pkg_proto_tinylink_proto protoreflect.FileDescriptor
 
 const file_pkg_proto_tinylink_proto_rawDesc = "" +
@@ -110,10 +208,16 @@ const file_pkg_proto_tinylink_proto_rawDesc = "" +
 	"\x18pkg/proto/tinylink.proto\x12\x05proto\"\a\n" +
 	"\x05Empty\"$\n" +
 	"\x12GenerateIdResponse\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\x03R\x02id2D\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\".\n" +
+	"\x16GenerateIdRangeRequest\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x05R\x05count\"E\n" +
+	"\x17GenerateIdRangeResponse\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x03R\x05start\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count2\x96\x01\n" +
 	"\vIdGenerator\x125\n" +
 	"\n" +
-	"GenerateId\x12\f.proto.Empty\x1a\x19.proto.GenerateIdResponseB'Z%github.com/yin1895/tinylink/pkg/protob\x06proto3"
+	"GenerateId\x12\f.proto.Empty\x1a\x19.proto.GenerateIdResponse\x12P\n" +
+	"\x0fGenerateIdRange\x12\x1d.proto.GenerateIdRangeRequest\x1a\x1e.proto.GenerateIdRangeResponseB'Z%github.com/yin1895/tinylink/pkg/protob\x06proto3"
 
 var (
 	file_pkg_proto_tinylink_proto_rawDescOnce sync.Once
@@ -127,16 +231,20 @@ func file_pkg_proto_tinylink_proto_rawDescGZIP() []byte {
 	return file_pkg_proto_tinylink_proto_rawDescData
 }
 
-var file_pkg_proto_tinylink_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_pkg_proto_tinylink_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_pkg_proto_tinylink_proto_goTypes = []any{
-	(*Empty)(nil),              // 0: proto.Empty
-	(*GenerateIdResponse)(nil), // 1: proto.GenerateIdResponse
+	(*Empty)(nil),                   // 0: proto.Empty
+	(*GenerateIdResponse)(nil),      // 1: proto.GenerateIdResponse
+	(*GenerateIdRangeRequest)(nil),  // 2: proto.GenerateIdRangeRequest
+	(*GenerateIdRangeResponse)(nil), // 3: proto.GenerateIdRangeResponse
 }
 var file_pkg_proto_tinylink_proto_depIdxs = []int32{
 	0, // 0: proto.IdGenerator.GenerateId:input_type -> proto.Empty
-	1, // 1: proto.IdGenerator.GenerateId:output_type -> proto.GenerateIdResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
+	2, // 1: proto.IdGenerator.GenerateIdRange:input_type -> proto.GenerateIdRangeRequest
+	1, // 2: proto.IdGenerator.GenerateId:output_type -> proto.GenerateIdResponse
+	3, // 3: proto.IdGenerator.GenerateIdRange:output_type -> proto.GenerateIdRangeResponse
+	2, // [2:4] is the sub-list for method output_type
+	0, // [0:2] is the sub-list for method input_type
 	0, // [0:0] is the sub-list for extension type_name
 	0, // [0:0] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
@@ -153,7 +261,7 @@ func file_pkg_proto_tinylink_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_proto_tinylink_proto_rawDesc), len(file_pkg_proto_tinylink_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
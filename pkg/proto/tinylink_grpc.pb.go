@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	IdGenerator_GenerateId_FullMethodName = "/proto.IdGenerator/GenerateId"
+	IdGenerator_GenerateId_FullMethodName      = "/proto.IdGenerator/GenerateId"
+	IdGenerator_GenerateIdRange_FullMethodName = "/proto.IdGenerator/GenerateIdRange"
 )
 
 // IdGeneratorClient is the client API for IdGenerator service.
@@ -32,6 +33,9 @@ type IdGeneratorClient interface {
 	// 它接收一个空请求 (Empty)
 	// 并返回一个包含新 ID 的响应 (GenerateIdResponse)
 	GenerateId(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GenerateIdResponse, error)
+	// 申请一段连续 ID [start, start+count)，count 可能小于请求值
+	// 调用方在本地缓存号段，避免每个 ID 一次 RPC
+	GenerateIdRange(ctx context.Context, in *GenerateIdRangeRequest, opts ...grpc.CallOption) (*GenerateIdRangeResponse, error)
 }
 
 type idGeneratorClient struct {
@@ -52,6 +56,16 @@ func (c *idGeneratorClient) GenerateId(ctx context.Context, in *Empty, opts ...g
 	return out, nil
 }
 
+func (c *idGeneratorClient) GenerateIdRange(ctx context.Context, in *GenerateIdRangeRequest, opts ...grpc.CallOption) (*GenerateIdRangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GenerateIdRangeResponse)
+	err := c.cc.Invoke(ctx, IdGenerator_GenerateIdRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // IdGeneratorServer is the server API for IdGenerator service.
 // All implementations must embed UnimplementedIdGeneratorServer
 // for forward compatibility.
@@ -62,6 +76,9 @@ type IdGeneratorServer interface {
 	// 它接收一个空请求 (Empty)
 	// 并返回一个包含新 ID 的响应 (GenerateIdResponse)
 	GenerateId(context.Context, *Empty) (*GenerateIdResponse, error)
+	// 申请一段连续 ID [start, start+count)，count 可能小于请求值
+	// 调用方在本地缓存号段，避免每个 ID 一次 RPC
+	GenerateIdRange(context.Context, *GenerateIdRangeRequest) (*GenerateIdRangeResponse, error)
 	mustEmbedUnimplementedIdGeneratorServer()
 }
 
@@ -75,6 +92,9 @@ type UnimplementedIdGeneratorServer struct{}
 func (UnimplementedIdGeneratorServer) GenerateId(context.Context, *Empty) (*GenerateIdResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GenerateId not implemented")
 }
+func (UnimplementedIdGeneratorServer) GenerateIdRange(context.Context, *GenerateIdRangeRequest) (*GenerateIdRangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateIdRange not implemented")
+}
 func (UnimplementedIdGeneratorServer) mustEmbedUnimplementedIdGeneratorServer() {}
 func (UnimplementedIdGeneratorServer) testEmbeddedByValue()                     {}
 
@@ -114,6 +134,24 @@ func _IdGenerator_GenerateId_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _IdGenerator_GenerateIdRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateIdRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdGeneratorServer).GenerateIdRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IdGenerator_GenerateIdRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdGeneratorServer).GenerateIdRange(ctx, req.(*GenerateIdRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // IdGenerator_ServiceDesc is the grpc.ServiceDesc for IdGenerator service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -125,6 +163,10 @@ var IdGenerator_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GenerateId",
 			Handler:    _IdGenerator_GenerateId_Handler,
 		},
+		{
+			MethodName: "GenerateIdRange",
+			Handler:    _IdGenerator_GenerateIdRange_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/proto/tinylink.proto",